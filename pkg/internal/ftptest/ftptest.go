@@ -0,0 +1,348 @@
+// Package ftptest runs an in-memory FTPS server with explicit TLS for the tests of the FTPS clients
+package ftptest
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Server keeps files and directories in memory, paths are absolute and slash separated
+type Server struct {
+	// Host and Port of the control connection
+	Host string
+	Port string
+	// CertPEM is the self-signed certificate of the server, valid for 127.0.0.1
+	CertPEM []byte
+
+	listener  net.Listener
+	tlsConfig *tls.Config
+
+	mu    sync.Mutex
+	files map[string][]byte
+	dirs  map[string]bool
+	stall bool
+}
+
+// NewServer starts a server with an empty root directory, closed when the test ends
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	// borrow the self-signed certificate of httptest
+	https := httptest.NewTLSServer(http.NotFoundHandler())
+	cert := https.TLS.Certificates[0]
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: https.Certificate().Raw})
+	https.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	s := &Server{
+		Host:      host,
+		Port:      port,
+		CertPEM:   certPEM,
+		listener:  listener,
+		tlsConfig: &tls.Config{Certificates: []tls.Certificate{cert}},
+		files:     map[string][]byte{},
+		dirs:      map[string]bool{"/": true},
+	}
+
+	go s.serve()
+	t.Cleanup(func() { s.listener.Close() })
+
+	return s
+}
+
+// File returns the content of the file at p
+func (s *Server) File(p string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.files[p]
+	return data, ok
+}
+
+// SetFile creates or replaces the file at p, along with its parent directories
+func (s *Server) SetFile(p string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for dir := path.Dir(p); !s.dirs[dir]; dir = path.Dir(dir) {
+		s.dirs[dir] = true
+	}
+	s.files[p] = data
+}
+
+// Files returns the paths of every file
+func (s *Server) Files() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	paths := make([]string, 0, len(s.files))
+	for p := range s.files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	return paths
+}
+
+// IsDir reports whether the directory p exists
+func (s *Server) IsDir(p string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.dirs[p]
+}
+
+// Stall makes downloads send half of the file then hang until the client closes the data connection
+func (s *Server) Stall(stall bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stall = stall
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+// session is the state of one control connection
+type session struct {
+	s       *Server
+	conn    net.Conn
+	r       *bufio.Reader
+	passive net.Listener
+	rename  string
+}
+
+func (s *Server) handle(conn net.Conn) {
+	ss := &session{s: s, conn: conn, r: bufio.NewReader(conn)}
+	defer func() {
+		ss.conn.Close()
+		if ss.passive != nil {
+			ss.passive.Close()
+		}
+	}()
+
+	ss.reply(220, "ready")
+
+	for {
+		line, err := ss.r.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		cmd, arg, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+		if !ss.command(strings.ToUpper(cmd), arg) {
+			return
+		}
+	}
+}
+
+// command runs one command, returning false when the connection must be closed
+func (ss *session) command(cmd, arg string) bool {
+	s := ss.s
+
+	switch cmd {
+	case "AUTH":
+		ss.reply(234, "AUTH TLS ok")
+		ss.conn = tls.Server(ss.conn, s.tlsConfig)
+		ss.r = bufio.NewReader(ss.conn)
+	case "USER":
+		ss.reply(331, "password required")
+	case "PASS":
+		ss.reply(230, "logged in")
+	case "TYPE", "PBSZ", "PROT", "OPTS":
+		ss.reply(200, "ok")
+	case "EPSV":
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			ss.reply(425, err.Error())
+			break
+		}
+		if ss.passive != nil {
+			ss.passive.Close()
+		}
+		ss.passive = l
+		ss.reply(229, fmt.Sprintf("Entering Extended Passive Mode (|||%d|)", l.Addr().(*net.TCPAddr).Port))
+	case "STOR":
+		ss.stor(arg)
+	case "RETR":
+		ss.retr(arg)
+	case "LIST":
+		ss.list(arg)
+	case "DELE":
+		s.mu.Lock()
+		_, ok := s.files[arg]
+		delete(s.files, arg)
+		s.mu.Unlock()
+		ss.ok(ok, 250)
+	case "MKD":
+		s.mu.Lock()
+		ok := !s.dirs[arg] && s.dirs[path.Dir(arg)]
+		if ok {
+			s.dirs[arg] = true
+		}
+		s.mu.Unlock()
+		ss.ok(ok, 257)
+	case "RNFR":
+		s.mu.Lock()
+		_, ok := s.files[arg]
+		s.mu.Unlock()
+		ss.rename = arg
+		if ok {
+			ss.reply(350, "ready for RNTO")
+		} else {
+			ss.reply(550, "file unavailable")
+		}
+	case "RNTO":
+		s.mu.Lock()
+		data, ok := s.files[ss.rename]
+		if ok {
+			delete(s.files, ss.rename)
+			s.files[arg] = data
+		}
+		s.mu.Unlock()
+		ss.ok(ok, 250)
+	case "QUIT":
+		ss.reply(221, "bye")
+		return false
+	default:
+		ss.reply(502, "not implemented")
+	}
+
+	return true
+}
+
+func (ss *session) stor(p string) {
+	s := ss.s
+
+	s.mu.Lock()
+	ok := s.dirs[path.Dir(p)]
+	s.mu.Unlock()
+
+	data, ok := ss.transfer(ok, func(conn net.Conn) ([]byte, error) {
+		return io.ReadAll(conn)
+	})
+	if ok {
+		s.mu.Lock()
+		s.files[p] = data
+		s.mu.Unlock()
+	}
+}
+
+func (ss *session) retr(p string) {
+	s := ss.s
+
+	s.mu.Lock()
+	data, ok := s.files[p]
+	stall := s.stall
+	s.mu.Unlock()
+
+	ss.transfer(ok, func(conn net.Conn) ([]byte, error) {
+		if !stall {
+			_, err := conn.Write(data)
+			return nil, err
+		}
+
+		if _, err := conn.Write(data[:len(data)/2]); err != nil {
+			return nil, err
+		}
+		// hang until the client gives up
+		_, err := io.Copy(io.Discard, conn)
+		return nil, err
+	})
+}
+
+func (ss *session) list(dir string) {
+	s := ss.s
+
+	s.mu.Lock()
+	var lines []string
+	for p := range s.dirs {
+		if p != "/" && path.Dir(p) == dir {
+			lines = append(lines, fmt.Sprintf("drwxr-xr-x 1 ftp ftp 0 Jan 02 15:04 %s\r\n", path.Base(p)))
+		}
+	}
+	for p, data := range s.files {
+		if path.Dir(p) == dir {
+			lines = append(lines, fmt.Sprintf("-rw-r--r-- 1 ftp ftp %d Jan 02 15:04 %s\r\n", len(data), path.Base(p)))
+		}
+	}
+	ok := s.dirs[dir]
+	s.mu.Unlock()
+
+	sort.Strings(lines)
+	ss.transfer(ok, func(conn net.Conn) ([]byte, error) {
+		_, err := io.WriteString(conn, strings.Join(lines, ""))
+		return nil, err
+	})
+}
+
+// transfer accepts the data connection opened after EPSV and runs fn on it, replying 550 when ok is false
+func (ss *session) transfer(ok bool, fn func(conn net.Conn) ([]byte, error)) ([]byte, bool) {
+	if ss.passive == nil {
+		ss.reply(425, "use EPSV first")
+		return nil, false
+	}
+
+	raw, err := ss.passive.Accept()
+	ss.passive.Close()
+	ss.passive = nil
+	if err != nil {
+		ss.reply(425, err.Error())
+		return nil, false
+	}
+
+	if !ok {
+		raw.Close()
+		ss.reply(550, "file unavailable")
+		return nil, false
+	}
+
+	ss.reply(150, "opening data connection")
+
+	conn := tls.Server(raw, ss.s.tlsConfig)
+	data, err := fn(conn)
+	conn.Close()
+
+	if err != nil {
+		ss.reply(426, err.Error())
+		return nil, false
+	}
+
+	ss.reply(226, "transfer complete")
+	return data, true
+}
+
+func (ss *session) ok(ok bool, code int) {
+	if ok {
+		ss.reply(code, "ok")
+	} else {
+		ss.reply(550, "file unavailable")
+	}
+}
+
+func (ss *session) reply(code int, msg string) {
+	fmt.Fprintf(ss.conn, "%d %s\r\n", code, msg)
+}
@@ -0,0 +1,101 @@
+// Package watch holds the polling loop and the state file shared by the watchers of pcommon,
+// such as network.SFTPWatcher and google_sheets.SheetSync
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Run calls poll every interval until ctx is cancelled, passing its errors to onError
+func Run(ctx context.Context, interval time.Duration, poll func(ctx context.Context) error, onError func(err error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := poll(ctx); err != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// JSONFile is a map of values persisted as JSON in a local file, created on first write
+type JSONFile[V any] struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewJSONFile[V any](path string) *JSONFile[V] {
+	return &JSONFile[V]{path: path}
+}
+
+// Get returns the value of key and whether it is set
+func (f *JSONFile[V]) Get(key string) (V, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var zero V
+
+	values, err := f.load()
+	if err != nil {
+		return zero, false, err
+	}
+
+	v, ok := values[key]
+	return v, ok, nil
+}
+
+// Put sets the value of key
+func (f *JSONFile[V]) Put(key string, v V) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	values, err := f.load()
+	if err != nil {
+		return err
+	}
+
+	values[key] = v
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first so a crash never leaves a truncated file
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, f.path)
+}
+
+func (f *JSONFile[V]) load() (map[string]V, error) {
+	values := map[string]V{}
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return values, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", f.path, err)
+	}
+
+	return values, nil
+}
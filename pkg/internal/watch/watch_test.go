@@ -0,0 +1,38 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJSONFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	f := NewJSONFile[int](path)
+	if _, ok, err := f.Get("a"); ok || err != nil {
+		t.Fatalf("expected a missing file to be empty, got %v, %v", ok, err)
+	}
+
+	if err := f.Put("a", 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := f.Put("b", 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// a new handle reads the values back from the file
+	if v, ok, err := NewJSONFile[int](path).Get("a"); v != 1 || !ok || err != nil {
+		t.Errorf("expected 1, got %v, %v, %v", v, ok, err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("expected the temporary file to be renamed, got %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := f.Get("a"); err == nil {
+		t.Error("expected an error for a corrupt file")
+	}
+}
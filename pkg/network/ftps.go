@@ -2,68 +2,41 @@ package network
 
 import (
 	"bytes"
-	"crypto/tls"
-	"fmt"
+	"context"
 	"strings"
-
-	"github.com/jlaffaye/ftp"
 )
 
-func getFTPSConnection(hostname, username, password, port string) (*ftp.ServerConn, error) {
-	cfg := tls.Config{
-		InsecureSkipVerify: true,
-		ServerName:         hostname,
-		ClientSessionCache: tls.NewLRUClientSessionCache(32),
-	}
-
-	fullHost := fmt.Sprintf("%s:%s", hostname, port)
-
-	conn, err := ftp.Dial(fullHost, ftp.DialWithExplicitTLS(&cfg))
-	if err != nil {
-		return nil, err
-	}
-
-	err = conn.Login(username, password)
-	if err != nil {
-		return nil, err
-	}
-
-	return conn, nil
-}
-
+// UploadFileToFTPS uploads content to dstFile over explicit FTPS, verifying the server certificate
+// against the system pool. Use NewFTPSClient to trust a partner CA or a pinned certificate.
 func UploadFileToFTPS(hostname, username, password, dstFile, port, content string) error {
-	conn, err := getFTPSConnection(hostname, username, password, port)
-	if err != nil {
-		return err
-	}
+	ctx := context.Background()
 
-	defer conn.Quit()
-
-	err = conn.Stor(dstFile, strings.NewReader(content))
+	client, err := NewFTPSClient(ctx, FTPSConfig{Host: hostname, Port: port, Username: username, Password: password})
 	if err != nil {
 		return err
 	}
 
-	return nil
+	defer client.Close()
+
+	return client.Upload(ctx, strings.NewReader(content), dstFile)
 }
 
+// ReadFileFromFTPS returns the content of srcFile read over explicit FTPS, verifying the server
+// certificate against the system pool
 func ReadFileFromFTPS(hostname, username, password, srcFile, port string) (string, error) {
-	conn, err := getFTPSConnection(hostname, username, password, port)
-	if err != nil {
-		return "", err
-	}
-
-	defer conn.Quit()
+	ctx := context.Background()
 
-	res, err := conn.Retr(srcFile)
+	client, err := NewFTPSClient(ctx, FTPSConfig{Host: hostname, Port: port, Username: username, Password: password})
 	if err != nil {
 		return "", err
 	}
 
-	defer res.Close()
+	defer client.Close()
 
 	b := new(bytes.Buffer)
-	b.ReadFrom(res)
+	if _, err := client.Download(ctx, srcFile, b); err != nil {
+		return "", err
+	}
 
 	return b.String(), nil
 }
@@ -0,0 +1,285 @@
+package network
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jlaffaye/ftp"
+)
+
+const (
+	// DefaultFTPSTimeout is used for dialing when FTPSConfig.Timeout is not set
+	DefaultFTPSTimeout = 30 * time.Second
+)
+
+// ErrCertificateMismatch is returned when the FTPS server certificate does not match any pinned fingerprint
+var ErrCertificateMismatch = errors.New("tls: certificate does not match pinned fingerprint")
+
+// FTPSConfig configures an FTPSClient
+type FTPSConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+
+	// ImplicitTLS connects with TLS right away (usually port 990) instead of upgrading with AUTH TLS
+	ImplicitTLS bool
+	// ServerName overrides the name used to verify the certificate, defaults to Host
+	ServerName string
+	// RootCAsPEM holds PEM encoded CA certificates trusted in addition to the system pool
+	RootCAsPEM []byte
+	// PinnedCertSHA256 restricts the server certificate to the given hex encoded SHA256
+	// fingerprints (colons allowed). When set, the fingerprint replaces chain verification
+	// so self-signed partner certificates can be trusted.
+	PinnedCertSHA256 []string
+	// InsecureSkipVerify disables certificate verification. Explicit opt-in, never use it for PHI transfers.
+	InsecureSkipVerify bool
+
+	// DisableEPSV forces the legacy PASV command for passive data connections,
+	// needed by some servers behind firewalls that mishandle EPSV
+	DisableEPSV bool
+	// Timeout bounds the dial of the control and data connections, defaults to DefaultFTPSTimeout
+	Timeout time.Duration
+}
+
+// FTPSClient keeps a logged in FTPS control connection open for several operations.
+// Like the underlying connection it must not be used concurrently, and it is closed
+// when a context passed to one of its methods is cancelled mid operation.
+type FTPSClient struct {
+	conn *ftp.ServerConn
+
+	mu   sync.Mutex
+	data net.Conn // data connection of the last transfer, closed to abort it
+}
+
+// NewFTPSClient connects and logs in to the FTPS server described by config
+func NewFTPSClient(ctx context.Context, config FTPSConfig) (*FTPSClient, error) {
+	if config.Host == "" || config.Port == "" {
+		return nil, errors.New("ftps host and port are required")
+	}
+
+	tlsConfig, err := config.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = DefaultFTPSTimeout
+	}
+
+	client := &FTPSClient{}
+
+	options := []ftp.DialOption{
+		ftp.DialWithDialFunc(client.dialer(ctx, timeout, tlsConfig, config.ImplicitTLS)),
+		ftp.DialWithDisabledEPSV(config.DisableEPSV),
+	}
+
+	if config.ImplicitTLS {
+		options = append(options, ftp.DialWithTLS(tlsConfig))
+	} else {
+		options = append(options, ftp.DialWithExplicitTLS(tlsConfig))
+	}
+
+	conn, err := ftp.Dial(net.JoinHostPort(config.Host, config.Port), options...)
+	if err != nil {
+		return nil, err
+	}
+	client.conn = conn
+
+	stop := client.watch(ctx)
+	err = conn.Login(config.Username, config.Password)
+	if err = stop(err); err != nil {
+		conn.Quit()
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// dialer returns the dial function of the connection. The first call dials the control connection
+// within ctx, the next ones dial the TLS data connections, recorded so that a cancelled
+// context can abort a stalled transfer.
+func (c *FTPSClient) dialer(ctx context.Context, timeout time.Duration, tlsConfig *tls.Config, implicitTLS bool) func(network, address string) (net.Conn, error) {
+	d := &net.Dialer{Timeout: timeout}
+	control := true
+
+	return func(network, address string) (net.Conn, error) {
+		if control {
+			control = false
+
+			conn, err := d.DialContext(ctx, network, address)
+			if err != nil || !implicitTLS {
+				return conn, err
+			}
+			return tls.Client(conn, tlsConfig), nil
+		}
+
+		conn, err := d.Dial(network, address)
+		if err != nil {
+			return nil, err
+		}
+
+		data := tls.Client(conn, tlsConfig)
+
+		c.mu.Lock()
+		c.data = data
+		c.mu.Unlock()
+
+		return data, nil
+	}
+}
+
+func (config FTPSConfig) tlsConfig() (*tls.Config, error) {
+	serverName := config.ServerName
+	if serverName == "" {
+		serverName = config.Host
+	}
+
+	cfg := &tls.Config{
+		ServerName:         serverName,
+		ClientSessionCache: tls.NewLRUClientSessionCache(32),
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
+
+	if len(config.RootCAsPEM) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(config.RootCAsPEM) {
+			return nil, errors.New("no valid certificate found in RootCAsPEM")
+		}
+		cfg.RootCAs = pool
+	}
+
+	if len(config.PinnedCertSHA256) > 0 {
+		pinned := map[string]bool{}
+		for _, fp := range config.PinnedCertSHA256 {
+			pinned[normalizeCertFingerprint(fp)] = true
+		}
+
+		// chain verification is replaced by the fingerprint check below
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return ErrCertificateMismatch
+			}
+
+			sum := sha256.Sum256(rawCerts[0])
+			if !pinned[hex.EncodeToString(sum[:])] {
+				return ErrCertificateMismatch
+			}
+			return nil
+		}
+	}
+
+	return cfg, nil
+}
+
+func normalizeCertFingerprint(fp string) string {
+	fp = strings.ReplaceAll(strings.TrimSpace(fp), ":", "")
+	return strings.ToLower(fp)
+}
+
+// watch closes the connection, and the data connection of a running transfer, if ctx is
+// cancelled before the returned stop function is called. stop waits for the watcher to exit, so that it cannot close the connection
+// during the next operation, and returns the context error in place of err when the
+// operation was aborted.
+func (c *FTPSClient) watch(ctx context.Context) func(err error) error {
+	if ctx.Done() == nil {
+		return func(err error) error { return err }
+	}
+
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-ctx.Done():
+			c.mu.Lock()
+			if c.data != nil {
+				c.data.Close()
+			}
+			c.mu.Unlock()
+			c.conn.Quit()
+		case <-done:
+		}
+	}()
+
+	return func(err error) error {
+		close(done)
+		<-exited
+		if err != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+}
+
+// Close logs out and closes the control connection
+func (c *FTPSClient) Close() error {
+	return c.conn.Quit()
+}
+
+// Upload streams r into dstFile
+func (c *FTPSClient) Upload(ctx context.Context, r io.Reader, dstFile string) error {
+	stop := c.watch(ctx)
+	return stop(c.conn.Stor(dstFile, r))
+}
+
+// Download streams srcFile into w
+func (c *FTPSClient) Download(ctx context.Context, srcFile string, w io.Writer) (int64, error) {
+	stop := c.watch(ctx)
+
+	res, err := c.conn.Retr(srcFile)
+	if err != nil {
+		return 0, stop(err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		res.SetDeadline(deadline)
+	}
+
+	n, err := io.Copy(w, res)
+	if cerr := res.Close(); err == nil {
+		err = cerr
+	}
+
+	return n, stop(err)
+}
+
+// List returns the entries of dir
+func (c *FTPSClient) List(ctx context.Context, dir string) ([]*ftp.Entry, error) {
+	stop := c.watch(ctx)
+	entries, err := c.conn.List(dir)
+	return entries, stop(err)
+}
+
+// Delete removes the remote file
+func (c *FTPSClient) Delete(ctx context.Context, p string) error {
+	stop := c.watch(ctx)
+	return stop(c.conn.Delete(p))
+}
+
+// Rename moves oldname to newname
+func (c *FTPSClient) Rename(ctx context.Context, oldname, newname string) error {
+	stop := c.watch(ctx)
+	return stop(c.conn.Rename(oldname, newname))
+}
+
+// MakeDir creates the remote directory
+func (c *FTPSClient) MakeDir(ctx context.Context, dir string) error {
+	stop := c.watch(ctx)
+	return stop(c.conn.MakeDir(dir))
+}
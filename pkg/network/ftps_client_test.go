@@ -0,0 +1,158 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/phil-inc/pcommon/pkg/internal/ftptest"
+)
+
+func TestFTPSConfig_PinnedCertificate(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	raw := server.Certificate().Raw
+	sum := sha256.Sum256(raw)
+
+	// fingerprints are accepted in the colon separated upper case form printed by openssl
+	var parts []string
+	for _, b := range sum {
+		parts = append(parts, strings.ToUpper(hex.EncodeToString([]byte{b})))
+	}
+
+	cfg, err := FTPSConfig{Host: "ftps.partner.com", PinnedCertSHA256: []string{strings.Join(parts, ":")}}.tlsConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.ServerName != "ftps.partner.com" {
+		t.Errorf("expected server name to default to host, got %s", cfg.ServerName)
+	}
+
+	if err := cfg.VerifyPeerCertificate([][]byte{raw}, nil); err != nil {
+		t.Errorf("expected pinned certificate to be accepted: %v", err)
+	}
+
+	if err := cfg.VerifyPeerCertificate([][]byte{[]byte("other")}, nil); !errors.Is(err, ErrCertificateMismatch) {
+		t.Errorf("expected ErrCertificateMismatch, got %v", err)
+	}
+}
+
+func TestFTPSConfig_Verification(t *testing.T) {
+	cfg, err := FTPSConfig{Host: "ftps.partner.com"}.tlsConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.InsecureSkipVerify || cfg.VerifyPeerCertificate != nil {
+		t.Errorf("expected certificates to be verified by default")
+	}
+
+	if _, err := (FTPSConfig{Host: "ftps.partner.com", RootCAsPEM: []byte("not a pem")}).tlsConfig(); err == nil {
+		t.Errorf("expected error for invalid CA bundle")
+	}
+}
+
+func TestNewFTPSClient_RequiresAddress(t *testing.T) {
+	if _, err := NewFTPSClient(context.Background(), FTPSConfig{Host: "ftps.partner.com"}); err == nil {
+		t.Errorf("expected error when port is missing")
+	}
+}
+
+// newTestFTPSClient connects to an in-process FTPS server trusting its certificate
+func newTestFTPSClient(t *testing.T) (*FTPSClient, *ftptest.Server) {
+	t.Helper()
+
+	server := ftptest.NewServer(t)
+	client, err := NewFTPSClient(context.Background(), FTPSConfig{
+		Host:       server.Host,
+		Port:       server.Port,
+		Username:   "phil",
+		Password:   "secret",
+		RootCAsPEM: server.CertPEM,
+	})
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	return client, server
+}
+
+func TestFTPSClient_Transfers(t *testing.T) {
+	client, server := newTestFTPSClient(t)
+	ctx := context.Background()
+
+	if err := client.MakeDir(ctx, "/outbound"); err != nil {
+		t.Fatalf("unexpected mkdir error: %v", err)
+	}
+	if err := client.Upload(ctx, strings.NewReader("id,name\n"), "/outbound/claims.csv"); err != nil {
+		t.Fatalf("unexpected upload error: %v", err)
+	}
+
+	var b bytes.Buffer
+	if n, err := client.Download(ctx, "/outbound/claims.csv", &b); err != nil || n != 8 || b.String() != "id,name\n" {
+		t.Fatalf("unexpected download %q (%d bytes, %v)", b.String(), n, err)
+	}
+
+	if err := client.Rename(ctx, "/outbound/claims.csv", "/outbound/claims-1.csv"); err != nil {
+		t.Fatalf("unexpected rename error: %v", err)
+	}
+
+	entries, err := client.List(ctx, "/outbound")
+	if err != nil || len(entries) != 1 || entries[0].Name != "claims-1.csv" || entries[0].Size != 8 {
+		t.Fatalf("unexpected entries %v (%v)", entries, err)
+	}
+
+	if err := client.Delete(ctx, "/outbound/claims-1.csv"); err != nil {
+		t.Fatalf("unexpected delete error: %v", err)
+	}
+	if files := server.Files(); len(files) != 0 {
+		t.Errorf("expected the file to be deleted, got %v", files)
+	}
+}
+
+func TestFTPSClient_CancelDuringDownload(t *testing.T) {
+	client, server := newTestFTPSClient(t)
+	server.SetFile("/outbound/large.csv", bytes.Repeat([]byte("x"), 1<<16))
+	server.Stall(true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := client.Download(ctx, "/outbound/large.csv", io.Discard)
+		result <- err
+	}()
+
+	select {
+	case err := <-result:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("download was not aborted by the cancelled context")
+	}
+}
+
+func TestUploadFileToFTPS_VerifiesCertificate(t *testing.T) {
+	server := ftptest.NewServer(t)
+
+	// the self-signed test certificate is not in the system pool
+	err := UploadFileToFTPS(server.Host, "phil", "secret", "/claims.csv", server.Port, "id,name\n")
+	if err == nil {
+		t.Fatal("expected the untrusted certificate to be refused")
+	}
+	if files := server.Files(); len(files) != 0 {
+		t.Errorf("expected nothing to be uploaded, got %v", files)
+	}
+}
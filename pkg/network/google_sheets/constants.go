@@ -7,3 +7,12 @@ const (
 	SHEET_MIMETYPE      = "application/vnd.google-apps.spreadsheet"
 	DEFAULT_WRITE_RANGE = "sheet1"
 )
+
+// OAuth scopes for GoogleCreds.Scopes
+const (
+	SCOPE_DRIVE           = GOOGLE_DRIVE_LINK
+	SCOPE_DRIVE_READONLY  = "https://www.googleapis.com/auth/drive.readonly"
+	SCOPE_DRIVE_FILE      = "https://www.googleapis.com/auth/drive.file"
+	SCOPE_SHEETS          = "https://www.googleapis.com/auth/spreadsheets"
+	SCOPE_SHEETS_READONLY = "https://www.googleapis.com/auth/spreadsheets.readonly"
+)
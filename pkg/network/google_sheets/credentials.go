@@ -0,0 +1,72 @@
+package google_sheets
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// GoogleCredsFromJSON parses a service account key as downloaded from the cloud console
+func GoogleCredsFromJSON(data []byte) (*GoogleCreds, error) {
+	gc := &GoogleCreds{}
+	if err := json.Unmarshal(data, gc); err != nil {
+		return nil, fmt.Errorf("unable to parse google credentials: %w", err)
+	}
+
+	if gc.Type != "service_account" {
+		return nil, fmt.Errorf("unsupported google credentials type %q, expected service_account", gc.Type)
+	}
+
+	if gc.ClientEmail == "" || gc.PrivateKey == "" {
+		return nil, fmt.Errorf("google credentials are missing client_email or private_key")
+	}
+
+	return gc, nil
+}
+
+// GoogleCredsFromFile reads a service account key file
+func GoogleCredsFromFile(path string) (*GoogleCreds, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return GoogleCredsFromJSON(data)
+}
+
+// GoogleCredsFromBase64 decodes a base64 encoded service account key, as stored in most secret managers
+func GoogleCredsFromBase64(encoded string) (*GoogleCreds, error) {
+	encoded = strings.TrimSpace(encoded)
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		if data, err = base64.URLEncoding.DecodeString(encoded); err != nil {
+			return nil, fmt.Errorf("unable to decode google credentials: %w", err)
+		}
+	}
+
+	return GoogleCredsFromJSON(data)
+}
+
+// GoogleCredsFromEnv reads a service account key from the environment variable name,
+// holding either the JSON key or its base64 encoding
+func GoogleCredsFromEnv(name string) (*GoogleCreds, error) {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return nil, fmt.Errorf("environment variable %s is not set", name)
+	}
+
+	if strings.HasPrefix(value, "{") {
+		return GoogleCredsFromJSON([]byte(value))
+	}
+
+	return GoogleCredsFromBase64(value)
+}
+
+// DefaultGoogleCreds uses Application Default Credentials: GOOGLE_APPLICATION_CREDENTIALS,
+// the gcloud user credentials or the metadata server of the workload
+func DefaultGoogleCreds() *GoogleCreds {
+	return &GoogleCreds{UseDefaultCredentials: true}
+}
@@ -0,0 +1,97 @@
+package google_sheets
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func testServiceAccountJSON(t *testing.T, tokenURI string) []byte {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	data, err := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"project_id":   "phil-test",
+		"private_key":  string(keyPEM),
+		"client_email": "reports@phil-test.iam.gserviceaccount.com",
+		"token_uri":    tokenURI,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+func TestGoogleCredsFromEnv(t *testing.T) {
+	data := testServiceAccountJSON(t, "https://oauth2.googleapis.com/token")
+
+	t.Setenv("TEST_GOOGLE_CREDS", base64.StdEncoding.EncodeToString(data))
+	gc, err := GoogleCredsFromEnv("TEST_GOOGLE_CREDS")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gc.ClientEmail != "reports@phil-test.iam.gserviceaccount.com" || gc.ProjectID != "phil-test" {
+		t.Errorf("unexpected creds %+v", gc)
+	}
+
+	t.Setenv("TEST_GOOGLE_CREDS", string(data))
+	if _, err := GoogleCredsFromEnv("TEST_GOOGLE_CREDS"); err != nil {
+		t.Errorf("unexpected error reading raw JSON: %v", err)
+	}
+
+	if _, err := GoogleCredsFromJSON([]byte(`{"type": "authorized_user"}`)); err == nil {
+		t.Error("expected non service account credentials to be rejected")
+	}
+}
+
+func TestGoogleCreds_GetClientWithSubjectAndScopes(t *testing.T) {
+	var claims map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		parts := strings.Split(r.Form.Get("assertion"), ".")
+		if len(parts) == 3 {
+			payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+			json.Unmarshal(payload, &claims)
+		}
+		writeJSON(t, w, map[string]interface{}{"access_token": "token", "token_type": "Bearer", "expires_in": 3600})
+	}))
+	defer server.Close()
+
+	gc, err := GoogleCredsFromJSON(testServiceAccountJSON(t, server.URL+"/token"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	gc.Subject = "ops@phil.us"
+	gc.Scopes = []string{SCOPE_SHEETS_READONLY}
+
+	client, err := gc.GetClient(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the token is fetched on the first request
+	resp, err := client.Get(server.URL + "/token")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if claims["sub"] != "ops@phil.us" || claims["scope"] != SCOPE_SHEETS_READONLY {
+		t.Errorf("unexpected token claims %v", claims)
+	}
+}
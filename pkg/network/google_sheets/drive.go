@@ -0,0 +1,142 @@
+package google_sheets
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"google.golang.org/api/drive/v3"
+)
+
+const (
+	// DRIVE_FILE_FIELDS are the file fields returned by the drive operations
+	DRIVE_FILE_FIELDS = "id, name, description, mimeType, parents, webViewLink, createdTime, modifiedTime, size, driveId"
+
+	// Permission roles
+	ROLE_READER    = "reader"
+	ROLE_COMMENTER = "commenter"
+	ROLE_WRITER    = "writer"
+	ROLE_ORGANIZER = "organizer"
+	ROLE_OWNER     = "owner"
+
+	// Permission grantee types
+	GRANTEE_USER   = "user"
+	GRANTEE_GROUP  = "group"
+	GRANTEE_DOMAIN = "domain"
+	GRANTEE_ANYONE = "anyone"
+
+	// Export formats, CSV only exports the first tab of a spreadsheet
+	EXPORT_CSV  = "text/csv"
+	EXPORT_XLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	EXPORT_PDF  = "application/pdf"
+)
+
+// Share grants access to a file. Email is used for users and groups, Domain for domains.
+type Share struct {
+	Type    string
+	Role    string
+	Email   string
+	Domain  string
+	Notify  bool   // send a notification email, required when transferring ownership
+	Message string // included in the notification email
+}
+
+// ShareFile grants the permission to the file and returns the id of the permission
+func (s *SheetsService) ShareFile(ctx context.Context, fileId string, share Share) (string, error) {
+	p := &drive.Permission{Type: share.Type, Role: share.Role, EmailAddress: share.Email, Domain: share.Domain}
+
+	call := s.Drive.Permissions.Create(fileId, p).
+		SendNotificationEmail(share.Notify || share.Role == ROLE_OWNER).
+		TransferOwnership(share.Role == ROLE_OWNER).
+		SupportsAllDrives(true)
+	if share.Notify && share.Message != "" {
+		call = call.EmailMessage(share.Message)
+	}
+
+	resp, err := call.Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("unable to share file %s: %w", fileId, apiError(err))
+	}
+
+	return resp.Id, nil
+}
+
+// MoveFile moves the file out of its current folders into folderId
+func (s *SheetsService) MoveFile(ctx context.Context, fileId, folderId string) (*drive.File, error) {
+	f, err := s.Drive.Files.Get(fileId).Fields("parents").SupportsAllDrives(true).Context(ctx).Do()
+	if err != nil {
+		return nil, apiError(err)
+	}
+
+	f, err = s.Drive.Files.Update(fileId, &drive.File{}).
+		AddParents(folderId).
+		RemoveParents(strings.Join(f.Parents, ",")).
+		Fields(DRIVE_FILE_FIELDS).
+		SupportsAllDrives(true).
+		Context(ctx).
+		Do()
+	return f, apiError(err)
+}
+
+// CopyFile copies the file into folderId, an empty name keeps the name of the original
+func (s *SheetsService) CopyFile(ctx context.Context, fileId, folderId, name string) (*drive.File, error) {
+	f := &drive.File{Name: name}
+	if folderId != "" {
+		f.Parents = []string{folderId}
+	}
+
+	f, err := s.Drive.Files.Copy(fileId, f).
+		Fields(DRIVE_FILE_FIELDS).
+		SupportsAllDrives(true).
+		Context(ctx).
+		Do()
+	return f, apiError(err)
+}
+
+// ExportFile converts a google document to mimeType, e.g. EXPORT_XLSX, and returns its content.
+// Drive limits exports to 10MB.
+func (s *SheetsService) ExportFile(ctx context.Context, fileId, mimeType string) ([]byte, error) {
+	resp, err := s.Drive.Files.Export(fileId, mimeType).Context(ctx).Download()
+	if err != nil {
+		return nil, apiError(err)
+	}
+	defer resp.Body.Close()
+
+	return io.ReadAll(resp.Body)
+}
+
+// ListFolder returns one page of the files in the folder and the token of the next page,
+// empty on the last page. Pass an empty pageToken for the first page.
+func (s *SheetsService) ListFolder(ctx context.Context, folderId string, pageSize int64, pageToken string) ([]*drive.File, string, error) {
+	call := s.listFolderCall(folderId).PageSize(pageSize)
+	if pageToken != "" {
+		call = call.PageToken(pageToken)
+	}
+
+	resp, err := call.Context(ctx).Do()
+	if err != nil {
+		return nil, "", apiError(err)
+	}
+
+	return resp.Files, resp.NextPageToken, nil
+}
+
+// WalkFolder calls fn with every page of files in the folder, stopping at the first error
+func (s *SheetsService) WalkFolder(ctx context.Context, folderId string, fn func([]*drive.File) error) error {
+	return apiError(s.listFolderCall(folderId).Pages(ctx, func(list *drive.FileList) error {
+		return fn(list.Files)
+	}))
+}
+
+func (s *SheetsService) listFolderCall(folderId string) *drive.FilesListCall {
+	q := fmt.Sprintf("'%s' in parents and trashed = false", strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(folderId))
+
+	return s.Drive.Files.List().
+		Q(q).
+		Corpora("allDrives").
+		IncludeItemsFromAllDrives(true).
+		SupportsAllDrives(true).
+		OrderBy("name").
+		Fields("nextPageToken", "files("+DRIVE_FILE_FIELDS+")")
+}
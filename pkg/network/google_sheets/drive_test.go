@@ -0,0 +1,112 @@
+package google_sheets
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/drive/v3"
+)
+
+func TestSheetsService_CreateGoogleSheetInDrive(t *testing.T) {
+	srv := newTestSheetsService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/files" || r.URL.Query().Get("supportsAllDrives") != "true" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		writeJSON(t, w, map[string]interface{}{
+			"id": "file-id", "name": "Report", "parents": []string{"folder-id"}, "webViewLink": "https://docs.google.com/file-id",
+		})
+	})
+
+	f, err := srv.CreateGoogleSheetInDrive(context.Background(), "", "folder-id", "Report")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if f.Id != "file-id" || f.Title != "Report" || f.AlternateLink != "https://docs.google.com/file-id" || f.Parents[0].Id != "folder-id" {
+		t.Errorf("unexpected file %+v", f)
+	}
+}
+
+func TestSheetsService_ShareFile(t *testing.T) {
+	srv := newTestSheetsService(t, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/files/file-id/permissions" || q.Get("sendNotificationEmail") != "false" || q.Get("supportsAllDrives") != "true" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		writeJSON(t, w, map[string]interface{}{"id": "perm-id"})
+	})
+
+	id, err := srv.ShareFile(context.Background(), "file-id", Share{Type: GRANTEE_GROUP, Role: ROLE_READER, Email: "ops@phil.us"})
+	if err != nil || id != "perm-id" {
+		t.Errorf("expected perm-id, got %s, %v", id, err)
+	}
+}
+
+func TestSheetsService_MoveFile(t *testing.T) {
+	srv := newTestSheetsService(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(t, w, map[string]interface{}{"parents": []string{"old-a", "old-b"}})
+		case http.MethodPatch:
+			q := r.URL.Query()
+			if q.Get("addParents") != "new" || q.Get("removeParents") != "old-a,old-b" {
+				t.Errorf("unexpected update %s", r.URL)
+			}
+			writeJSON(t, w, map[string]interface{}{"id": "file-id", "parents": []string{"new"}})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	})
+
+	f, err := srv.MoveFile(context.Background(), "file-id", "new")
+	if err != nil || !reflect.DeepEqual(f.Parents, []string{"new"}) {
+		t.Errorf("unexpected result %+v, %v", f, err)
+	}
+}
+
+func TestSheetsService_ExportFile(t *testing.T) {
+	srv := newTestSheetsService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/files/file-id/export" || r.URL.Query().Get("mimeType") != EXPORT_CSV {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Write([]byte("ndc,name\n"))
+	})
+
+	b, err := srv.ExportFile(context.Background(), "file-id", EXPORT_CSV)
+	if err != nil || string(b) != "ndc,name\n" {
+		t.Errorf("unexpected export %q, %v", b, err)
+	}
+}
+
+func TestSheetsService_WalkFolder(t *testing.T) {
+	srv := newTestSheetsService(t, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("q") != `'it\'s' in parents and trashed = false` {
+			t.Errorf("unexpected query %s", q.Get("q"))
+		}
+
+		if q.Get("pageToken") == "" {
+			writeJSON(t, w, map[string]interface{}{"files": []interface{}{map[string]string{"id": "a"}}, "nextPageToken": "next"})
+			return
+		}
+		writeJSON(t, w, map[string]interface{}{"files": []interface{}{map[string]string{"id": "b"}}})
+	})
+
+	var ids []string
+	err := srv.WalkFolder(context.Background(), "it's", func(files []*drive.File) error {
+		for _, f := range files {
+			ids = append(ids, f.Id)
+		}
+		return nil
+	})
+	if err != nil || !reflect.DeepEqual(ids, []string{"a", "b"}) {
+		t.Errorf("unexpected files %v, %v", ids, err)
+	}
+
+	_, next, err := srv.ListFolder(context.Background(), "it's", 1, "")
+	if err != nil || next != "next" {
+		t.Errorf("expected next page token, got %q, %v", next, err)
+	}
+}
@@ -0,0 +1,63 @@
+package google_sheets
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"google.golang.org/api/googleapi"
+)
+
+// Errors returned by the SheetsService methods wrap one of these along with the
+// *googleapi.Error, check them with errors.Is
+var (
+	ErrNotFound         = errors.New("spreadsheet or file not found")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrQuotaExceeded    = errors.New("quota exceeded")
+	ErrInvalidRange     = errors.New("invalid range")
+)
+
+// apiError classifies an error returned by the google APIs
+func apiError(err error) error {
+	var gerr *googleapi.Error
+	if err == nil || !errors.As(err, &gerr) {
+		return err
+	}
+
+	var kind error
+	switch {
+	case gerr.Code == http.StatusNotFound:
+		kind = ErrNotFound
+	case isQuotaError(gerr.Code, gerr.Body):
+		kind = ErrQuotaExceeded
+	case gerr.Code == http.StatusForbidden || gerr.Code == http.StatusUnauthorized:
+		kind = ErrPermissionDenied
+	case gerr.Code == http.StatusBadRequest && isRangeMessage(gerr.Message):
+		kind = ErrInvalidRange
+	default:
+		return err
+	}
+
+	return fmt.Errorf("%w: %w", kind, err)
+}
+
+// isQuotaError reports whether a response is a rate limit error. Sheets v4 answers
+// 429 RESOURCE_EXHAUSTED, Drive answers 403 with a rateLimitExceeded reason.
+func isQuotaError(code int, body string) bool {
+	if code == http.StatusTooManyRequests {
+		return true
+	}
+
+	if code != http.StatusForbidden && code != http.StatusBadRequest {
+		return false
+	}
+
+	body = strings.ToLower(body)
+	return strings.Contains(body, "resource_exhausted") || strings.Contains(body, "ratelimitexceeded") || strings.Contains(body, "quotaexceeded")
+}
+
+func isRangeMessage(msg string) bool {
+	msg = strings.ToLower(msg)
+	return strings.Contains(msg, "parse range") || strings.Contains(msg, "exceeds grid limits")
+}
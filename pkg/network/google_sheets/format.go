@@ -0,0 +1,149 @@
+package google_sheets
+
+import (
+	"context"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// Number format patterns, see https://developers.google.com/sheets/api/guides/formats
+var (
+	FORMAT_NUMBER    = sheets.NumberFormat{Type: "NUMBER", Pattern: "#,##0.00"}
+	FORMAT_INTEGER   = sheets.NumberFormat{Type: "NUMBER", Pattern: "#,##0"}
+	FORMAT_CURRENCY  = sheets.NumberFormat{Type: "CURRENCY", Pattern: "$#,##0.00"}
+	FORMAT_PERCENT   = sheets.NumberFormat{Type: "PERCENT", Pattern: "0.00%"}
+	FORMAT_DATE      = sheets.NumberFormat{Type: "DATE", Pattern: "mm/dd/yyyy"}
+	FORMAT_DATE_TIME = sheets.NumberFormat{Type: "DATE_TIME", Pattern: "mm/dd/yyyy hh:mm:ss"}
+)
+
+// Conditional formatting condition types, values are passed to ConditionalFormat
+const (
+	CONDITION_NUMBER_GREATER = "NUMBER_GREATER"
+	CONDITION_NUMBER_LESS    = "NUMBER_LESS"
+	CONDITION_NUMBER_EQ      = "NUMBER_EQ"
+	CONDITION_TEXT_EQ        = "TEXT_EQ"
+	CONDITION_TEXT_CONTAINS  = "TEXT_CONTAINS"
+	CONDITION_BLANK          = "BLANK"
+	CONDITION_CUSTOM_FORMULA = "CUSTOM_FORMULA"
+)
+
+// CellRange is a block of cells of a tab. Indexes are 0 based, start inclusive and end exclusive,
+// an end of 0 leaves the range unbounded, e.g. CellRange{TabID: id, StartRow: 1} is every row below the header.
+type CellRange struct {
+	TabID    int64
+	StartRow int64
+	EndRow   int64
+	StartCol int64
+	EndCol   int64
+}
+
+func (r CellRange) gridRange() *sheets.GridRange {
+	return &sheets.GridRange{
+		SheetId:          r.TabID,
+		StartRowIndex:    r.StartRow,
+		EndRowIndex:      r.EndRow,
+		StartColumnIndex: r.StartCol,
+		EndColumnIndex:   r.EndCol,
+		ForceSendFields:  []string{"SheetId"},
+	}
+}
+
+// RGB builds a color from 0-255 components
+func RGB(red, green, blue int) *sheets.Color {
+	return &sheets.Color{Red: float64(red) / 255, Green: float64(green) / 255, Blue: float64(blue) / 255}
+}
+
+// FreezeRows keeps the first rows of the tab visible while scrolling
+func FreezeRows(tabId, rows int64) *sheets.Request {
+	return &sheets.Request{
+		UpdateSheetProperties: &sheets.UpdateSheetPropertiesRequest{
+			Properties: &sheets.SheetProperties{
+				SheetId:         tabId,
+				GridProperties:  &sheets.GridProperties{FrozenRowCount: rows, ForceSendFields: []string{"FrozenRowCount"}},
+				ForceSendFields: []string{"SheetId"},
+			},
+			Fields: "gridProperties.frozenRowCount",
+		},
+	}
+}
+
+// ColumnWidth sets the width in pixels of the columns [startCol, endCol)
+func ColumnWidth(tabId, startCol, endCol, pixels int64) *sheets.Request {
+	return &sheets.Request{
+		UpdateDimensionProperties: &sheets.UpdateDimensionPropertiesRequest{
+			Range:      columns(tabId, startCol, endCol),
+			Properties: &sheets.DimensionProperties{PixelSize: pixels},
+			Fields:     "pixelSize",
+		},
+	}
+}
+
+// AutoResizeColumns fits the width of the columns [startCol, endCol) to their content
+func AutoResizeColumns(tabId, startCol, endCol int64) *sheets.Request {
+	return &sheets.Request{
+		AutoResizeDimensions: &sheets.AutoResizeDimensionsRequest{Dimensions: columns(tabId, startCol, endCol)},
+	}
+}
+
+func columns(tabId, startCol, endCol int64) *sheets.DimensionRange {
+	return &sheets.DimensionRange{
+		SheetId:         tabId,
+		Dimension:       "COLUMNS",
+		StartIndex:      startCol,
+		EndIndex:        endCol,
+		ForceSendFields: []string{"SheetId"},
+	}
+}
+
+// NumberFormat applies a number, currency or date format, e.g. FORMAT_CURRENCY, to the range
+func NumberFormat(r CellRange, format sheets.NumberFormat) *sheets.Request {
+	return &sheets.Request{
+		RepeatCell: &sheets.RepeatCellRequest{
+			Range:  r.gridRange(),
+			Cell:   &sheets.CellData{UserEnteredFormat: &sheets.CellFormat{NumberFormat: &format}},
+			Fields: "userEnteredFormat.numberFormat",
+		},
+	}
+}
+
+// Bold makes the text of the range bold, e.g. the header row
+func Bold(r CellRange) *sheets.Request {
+	return &sheets.Request{
+		RepeatCell: &sheets.RepeatCellRequest{
+			Range:  r.gridRange(),
+			Cell:   &sheets.CellData{UserEnteredFormat: &sheets.CellFormat{TextFormat: &sheets.TextFormat{Bold: true}}},
+			Fields: "userEnteredFormat.textFormat.bold",
+		},
+	}
+}
+
+// ConditionalFormat colors the background of the cells of the range matching the condition,
+// e.g. ConditionalFormat(r, CONDITION_NUMBER_LESS, RGB(244, 199, 195), "0")
+func ConditionalFormat(r CellRange, condition string, background *sheets.Color, values ...string) *sheets.Request {
+	cv := make([]*sheets.ConditionValue, 0, len(values))
+	for _, v := range values {
+		cv = append(cv, &sheets.ConditionValue{UserEnteredValue: v})
+	}
+
+	return &sheets.Request{
+		AddConditionalFormatRule: &sheets.AddConditionalFormatRuleRequest{
+			Rule: &sheets.ConditionalFormatRule{
+				Ranges: []*sheets.GridRange{r.gridRange()},
+				BooleanRule: &sheets.BooleanRule{
+					Condition: &sheets.BooleanCondition{Type: condition, Values: cv},
+					Format:    &sheets.CellFormat{BackgroundColor: background},
+				},
+			},
+		},
+	}
+}
+
+// FormatHeader freezes and bolds the first row of the tab and fits the columns to their content
+func (s *SheetsService) FormatHeader(ctx context.Context, sheetId string, tabId int64) error {
+	_, err := s.ApplyRequests(ctx, sheetId,
+		FreezeRows(tabId, 1),
+		Bold(CellRange{TabID: tabId, EndRow: 1}),
+		AutoResizeColumns(tabId, 0, 0),
+	)
+	return err
+}
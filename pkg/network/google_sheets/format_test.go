@@ -0,0 +1,52 @@
+package google_sheets
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestSheetsService_FormatHeader(t *testing.T) {
+	var body struct {
+		Requests []map[string]json.RawMessage `json:"requests"`
+	}
+	srv := newTestSheetsService(t, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		json.Unmarshal(b, &body)
+		writeJSON(t, w, map[string]interface{}{})
+	})
+
+	if err := srv.FormatHeader(context.Background(), "sheet-id", 3); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(body.Requests) != 3 {
+		t.Fatalf("expected 3 requests, got %v", body.Requests)
+	}
+	for i, kind := range []string{"updateSheetProperties", "repeatCell", "autoResizeDimensions"} {
+		if _, ok := body.Requests[i][kind]; !ok {
+			t.Errorf("expected request %d to be %s, got %v", i, kind, body.Requests[i])
+		}
+	}
+
+	expected := `{"cell":{"userEnteredFormat":{"textFormat":{"bold":true}}},"fields":"userEnteredFormat.textFormat.bold","range":{"endRowIndex":1,"sheetId":3}}`
+	if string(body.Requests[1]["repeatCell"]) != expected {
+		t.Errorf("unexpected bold request %s", body.Requests[1]["repeatCell"])
+	}
+}
+
+func TestConditionalFormat(t *testing.T) {
+	req := ConditionalFormat(CellRange{StartRow: 1, StartCol: 2, EndCol: 3}, CONDITION_NUMBER_LESS, RGB(255, 0, 0), "0")
+
+	b, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `{"addConditionalFormatRule":{"rule":{"booleanRule":{"condition":{"type":"NUMBER_LESS","values":[{"userEnteredValue":"0"}]},"format":{"backgroundColor":{"red":1}}},"ranges":[{"endColumnIndex":3,"sheetId":0,"startColumnIndex":2,"startRowIndex":1}]}}}`
+	if string(b) != expected {
+		t.Errorf("expected %s, got %s", expected, b)
+	}
+}
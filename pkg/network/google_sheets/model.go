@@ -12,6 +12,13 @@ type GoogleCreds struct {
 	TokenURI        string `json:"token_uri"`
 	ProviderCertURI string `json:"auth_provider_x509_cert_url"`
 	ClientCertURI   string `json:"client_x509_cert_url"`
+
+	// Subject is the Workspace user to impersonate through domain-wide delegation
+	Subject string `json:"-"`
+	// Scopes requested for the token, defaults to GOOGLE_DRIVE_LINK
+	Scopes []string `json:"-"`
+	// UseDefaultCredentials ignores the key fields and uses Application Default Credentials
+	UseDefaultCredentials bool `json:"-"`
 }
 
 type FileMetaData struct {
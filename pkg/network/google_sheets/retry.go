@@ -0,0 +1,242 @@
+package google_sheets
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RetryConfig controls how requests failing with a quota or transient server error are retried
+type RetryConfig struct {
+	// MaxRetries is the number of retries after the first attempt, 0 disables retries
+	MaxRetries int
+	// InitialBackoff is the maximum wait before the first retry, doubled on every retry
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between two attempts
+	MaxBackoff time.Duration
+}
+
+// Quota is a per minute request quota of a google project, each one is rate limited separately
+type Quota int
+
+const (
+	// QuotaSheetsRead counts the Sheets requests that only read, e.g. values.get
+	QuotaSheetsRead Quota = iota
+	// QuotaSheetsWrite counts the other Sheets requests, e.g. values.update or batchUpdate
+	QuotaSheetsWrite
+	// QuotaDrive counts the Drive requests, e.g. creating or moving files
+	QuotaDrive
+)
+
+var (
+	// DefaultRetryConfig is used by the transport installed by NewSheetsService
+	DefaultRetryConfig = RetryConfig{MaxRetries: 5, InitialBackoff: time.Second, MaxBackoff: 64 * time.Second}
+
+	// DefaultRequestsPerMinute limits the Sheets reads and, separately, the Sheets writes sent for
+	// a single google project, matching the default quotas of 60 requests per minute per user.
+	// Drive requests have a much higher quota and are not limited unless SetProjectRateLimit is
+	// called for QuotaDrive. 0 disables the limit.
+	DefaultRequestsPerMinute = 60
+
+	projectLimiters   = map[projectQuota]*rateLimiter{}
+	projectLimitersMu sync.Mutex
+)
+
+type projectQuota struct {
+	projectID string
+	quota     Quota
+}
+
+// SetProjectRateLimit changes the number of requests per minute allowed for one quota of a google
+// project, e.g. after a quota increase. 0 disables the limit.
+func SetProjectRateLimit(projectID string, quota Quota, requestsPerMinute int) {
+	projectLimiter(projectID, quota).setRate(requestsPerMinute)
+}
+
+func projectLimiter(projectID string, quota Quota) *rateLimiter {
+	projectLimitersMu.Lock()
+	defer projectLimitersMu.Unlock()
+
+	key := projectQuota{projectID: projectID, quota: quota}
+	l, ok := projectLimiters[key]
+	if !ok {
+		l = &rateLimiter{}
+		if quota != QuotaDrive {
+			l.setRate(DefaultRequestsPerMinute)
+		}
+		projectLimiters[key] = l
+	}
+
+	return l
+}
+
+// requestQuota returns the quota counting req. Sheets paths start with /v4/spreadsheets, reads
+// are the GET requests and the POST ones getting values or spreadsheets by data filter.
+func requestQuota(req *http.Request) Quota {
+	if !strings.HasPrefix(req.URL.Path, "/v4/spreadsheets") {
+		return QuotaDrive
+	}
+
+	if req.Method == http.MethodGet || strings.HasSuffix(req.URL.Path, "ByDataFilter") {
+		return QuotaSheetsRead
+	}
+
+	return QuotaSheetsWrite
+}
+
+// NewRetryTransport wraps base so that requests are rate limited per project and quota and retried
+// with exponential backoff on quota and transient server errors. NewSheetsService installs it,
+// use it when passing your own client with option.WithHTTPClient.
+func NewRetryTransport(base http.RoundTripper, projectID string) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &retryTransport{base: base, projectID: projectID, config: DefaultRetryConfig, sleep: sleepContext}
+}
+
+type retryTransport struct {
+	base      http.RoundTripper
+	projectID string
+	config    RetryConfig
+	sleep     func(ctx context.Context, d time.Duration) error
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	limiter := projectLimiter(t.projectID, requestQuota(req))
+
+	for attempt := 0; ; attempt++ {
+		if err := limiter.wait(ctx); err != nil {
+			return nil, err
+		}
+
+		r := req
+		if attempt > 0 && req.GetBody != nil {
+			// the body was consumed by the previous attempt
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r = req.Clone(ctx)
+			r.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(r)
+		if err != nil || attempt >= t.config.MaxRetries || !shouldRetry(req.Method, resp) {
+			return resp, err
+		}
+
+		// a streamed body cannot be sent again
+		if req.Body != nil && req.GetBody == nil {
+			return resp, nil
+		}
+
+		wait := t.backoff(attempt, resp)
+		resp.Body.Close()
+
+		if err := t.sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// shouldRetry reports whether the response is a quota error, or a transient server error of an
+// idempotent request: a POST such as an append may have been applied before the server failed.
+// The body is read to find quota reasons and put back for the caller.
+func shouldRetry(method string, resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return isIdempotent(method)
+	case http.StatusForbidden, http.StatusBadRequest:
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		return err == nil && isQuotaError(resp.StatusCode, string(body))
+	}
+
+	return false
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// backoff returns the wait before the next attempt, honouring Retry-After when the server sets it
+// up to MaxBackoff
+func (t *retryTransport) backoff(attempt int, resp *http.Response) time.Duration {
+	if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s > 0 {
+		return min(time.Duration(s)*time.Second, t.config.MaxBackoff)
+	}
+
+	d := t.config.InitialBackoff << attempt
+	if d <= 0 || d > t.config.MaxBackoff {
+		d = t.config.MaxBackoff
+	}
+
+	// full jitter spreads the retries of concurrent exports
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// rateLimiter spaces requests evenly so that at most rate requests are sent per minute
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func (l *rateLimiter) setRate(requestsPerMinute int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.interval = 0
+	if requestsPerMinute > 0 {
+		l.interval = time.Minute / time.Duration(requestsPerMinute)
+	}
+}
+
+// wait blocks until the next request slot or until ctx is done
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	if l.interval == 0 {
+		l.mu.Unlock()
+		return nil
+	}
+
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	if d := slot.Sub(now); d > 0 {
+		return sleepContext(ctx, d)
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package google_sheets
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/api/option"
+)
+
+// newRetryTestService returns a service whose requests go through the retry transport without sleeping
+func newRetryTestService(t *testing.T, handler http.HandlerFunc) (*SheetsService, *[]time.Duration) {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	SetProjectRateLimit(t.Name(), QuotaSheetsRead, 0)
+	SetProjectRateLimit(t.Name(), QuotaSheetsWrite, 0)
+	transport := NewRetryTransport(server.Client().Transport, t.Name()).(*retryTransport)
+
+	var waits []time.Duration
+	transport.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+
+	gc := &GoogleCreds{Type: "service_account", ClientEmail: "test@phil.us", TokenURI: server.URL + "/token"}
+	srv, err := NewSheetsService(context.Background(), gc, option.WithEndpoint(server.URL+"/"), option.WithHTTPClient(&http.Client{Transport: transport}))
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+
+	return srv, &waits
+}
+
+func TestRetryTransport_RetriesQuotaErrors(t *testing.T) {
+	attempts := 0
+	srv, waits := newRetryTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), "Trulicity") {
+			t.Errorf("attempt %d sent body %q", attempts, body)
+		}
+
+		switch attempts {
+		case 1:
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error": {"code": 429, "status": "RESOURCE_EXHAUSTED"}}`))
+		case 2:
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			writeJSON(t, w, map[string]interface{}{})
+		}
+	})
+
+	err := srv.ExportDataToGoogleSheetByIDAndRange(context.Background(), "sheet-id", "Sheet1", [][]interface{}{{"Trulicity"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+	if len(*waits) != 2 || (*waits)[0] > time.Second || (*waits)[1] != 7*time.Second {
+		t.Errorf("unexpected waits %v", *waits)
+	}
+}
+
+func TestRetryTransport_GivesUp(t *testing.T) {
+	attempts := 0
+	srv, _ := newRetryTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error": {"code": 403, "message": "Rate limit", "errors": [{"reason": "userRateLimitExceeded"}]}}`))
+	})
+
+	_, err := srv.GetModifiedDate(context.Background(), "file-id")
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("expected ErrQuotaExceeded, got %v", err)
+	}
+	if attempts != DefaultRetryConfig.MaxRetries+1 {
+		t.Errorf("expected %d attempts, got %d", DefaultRetryConfig.MaxRetries+1, attempts)
+	}
+}
+
+func TestRetryTransport_DoesNotRetryFailedPost(t *testing.T) {
+	attempts := 0
+	srv, _ := newRetryTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	// the append may have been applied, sending it again could duplicate the rows
+	if err := srv.AppendRows(context.Background(), "sheet-id", "Sheet1", [][]interface{}{{"Trulicity"}}); err == nil {
+		t.Fatal("expected an error")
+	}
+	if attempts != 1 {
+		t.Errorf("expected 1 attempt, got %d", attempts)
+	}
+}
+
+func TestRetryTransport_RetriesQuotaErrorsOfPost(t *testing.T) {
+	attempts := 0
+	srv, waits := newRetryTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		writeJSON(t, w, map[string]interface{}{})
+	})
+
+	if err := srv.AppendRows(context.Background(), "sheet-id", "Sheet1", [][]interface{}{{"Trulicity"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", attempts)
+	}
+	if len(*waits) != 1 || (*waits)[0] != DefaultRetryConfig.MaxBackoff {
+		t.Errorf("expected Retry-After to be capped at %v, got %v", DefaultRetryConfig.MaxBackoff, *waits)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	tests := []struct {
+		status   int
+		body     string
+		expected error
+	}{
+		{http.StatusNotFound, `{"error": {"code": 404, "message": "Requested entity was not found."}}`, ErrNotFound},
+		{http.StatusForbidden, `{"error": {"code": 403, "message": "The caller does not have permission"}}`, ErrPermissionDenied},
+		{http.StatusBadRequest, `{"error": {"code": 400, "message": "Unable to parse range: Missing!A1"}}`, ErrInvalidRange},
+	}
+
+	for _, test := range tests {
+		attempts := 0
+		srv, _ := newRetryTestService(t, func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(test.status)
+			w.Write([]byte(test.body))
+		})
+
+		_, err := srv.ReadDataFromGoogleSpreadSheetByIDAndRange(context.Background(), "sheet-id", "Missing!A1")
+		if !errors.Is(err, test.expected) {
+			t.Errorf("status %d: expected %v, got %v", test.status, test.expected, err)
+		}
+		if attempts != 1 {
+			t.Errorf("status %d: expected no retry, got %d attempts", test.status, attempts)
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := &rateLimiter{}
+	l.setRate(6000) // one request every 10ms
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.wait(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("expected requests to be spaced, took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	l.setRate(1)
+	l.wait(ctx)
+	if err := l.wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context error, got %v", err)
+	}
+}
+
+func TestRequestQuota(t *testing.T) {
+	tests := []struct {
+		method string
+		url    string
+		quota  Quota
+	}{
+		{http.MethodGet, "https://sheets.googleapis.com/v4/spreadsheets/id/values/Sheet1", QuotaSheetsRead},
+		{http.MethodPost, "https://sheets.googleapis.com/v4/spreadsheets/id/values:batchGetByDataFilter", QuotaSheetsRead},
+		{http.MethodPut, "https://sheets.googleapis.com/v4/spreadsheets/id/values/Sheet1", QuotaSheetsWrite},
+		{http.MethodPost, "https://sheets.googleapis.com/v4/spreadsheets/id:batchUpdate", QuotaSheetsWrite},
+		{http.MethodGet, "https://www.googleapis.com/drive/v3/files/id", QuotaDrive},
+		{http.MethodPost, "https://www.googleapis.com/upload/drive/v3/files", QuotaDrive},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.url, nil)
+		if got := requestQuota(req); got != test.quota {
+			t.Errorf("%s %s: expected quota %d, got %d", test.method, test.url, test.quota, got)
+		}
+	}
+}
+
+func TestProjectLimiter_SeparateQuotas(t *testing.T) {
+	read := projectLimiter(t.Name(), QuotaSheetsRead)
+	write := projectLimiter(t.Name(), QuotaSheetsWrite)
+	drive := projectLimiter(t.Name(), QuotaDrive)
+
+	if read == write || read == drive || write == drive {
+		t.Fatal("expected a limiter per quota")
+	}
+	if read != projectLimiter(t.Name(), QuotaSheetsRead) {
+		t.Error("expected the limiter of a quota to be shared by the project")
+	}
+
+	interval := time.Minute / time.Duration(DefaultRequestsPerMinute)
+	if read.interval != interval || write.interval != interval {
+		t.Errorf("expected Sheets quotas to default to %v, got %v and %v", interval, read.interval, write.interval)
+	}
+	if drive.interval != 0 {
+		t.Errorf("expected Drive to be unlimited by default, got %v", drive.interval)
+	}
+
+	SetProjectRateLimit(t.Name(), QuotaSheetsWrite, 0)
+	if read.interval != interval || write.interval != 0 {
+		t.Errorf("expected only the write quota to change, got %v and %v", read.interval, write.interval)
+	}
+}
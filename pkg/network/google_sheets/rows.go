@@ -0,0 +1,387 @@
+package google_sheets
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cast"
+)
+
+const (
+	// SHEET_TAG is the struct tag mapping a field to a header, e.g. `sheet:"Fill Date,layout=01/02/2006"`
+	SHEET_TAG = "sheet"
+
+	// DEFAULT_TIME_LAYOUT is used to write time fields without a layout option
+	DEFAULT_TIME_LAYOUT = "2006-01-02 15:04:05"
+)
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+
+	// cellRefRegex matches the cells of an A1 range after the sheet name, e.g. A, C5, C5:D or 2:5
+	cellRefRegex = regexp.MustCompile(`^([A-Za-z]*)([0-9]*)(:[A-Za-z]*[0-9]*)?$`)
+)
+
+// CellError reports a value that could not be decoded into its struct field
+type CellError struct {
+	Row    int    // 1 based sheet row
+	Column string // sheet column letter
+	Header string
+	Value  interface{}
+	Err    error
+}
+
+func (e *CellError) Error() string {
+	return fmt.Sprintf("row %d, column %s (%s): cannot decode %q: %v", e.Row, e.Column, e.Header, fmt.Sprint(e.Value), e.Err)
+}
+
+func (e *CellError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeErrors lists every cell that failed to decode. Rows with a failing cell are left out of the result.
+type DecodeErrors []*CellError
+
+func (e DecodeErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, c := range e {
+		msgs = append(msgs, c.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// sheetField describes a struct field mapped to a sheet column
+type sheetField struct {
+	index  int
+	header string
+	layout string
+}
+
+// sheetFields returns the tagged fields of t in declaration order
+func sheetFields(t reflect.Type) ([]sheetField, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%s is not a struct", t)
+	}
+
+	fields := []sheetField{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get(SHEET_TAG)
+		if tag == "" || tag == "-" || !f.IsExported() {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		sf := sheetField{index: i, header: strings.TrimSpace(parts[0])}
+		for _, opt := range parts[1:] {
+			if layout, ok := strings.CutPrefix(strings.TrimSpace(opt), "layout="); ok {
+				sf.layout = layout
+			}
+		}
+
+		fields = append(fields, sf)
+	}
+
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%s has no fields tagged with `%s`", t, SHEET_TAG)
+	}
+
+	return fields, nil
+}
+
+// ReadRows reads readRange and decodes every row below the header row into T,
+// matching headers to the `sheet` tags of T. Header matching ignores case and surrounding spaces.
+// Untagged fields and columns without a matching field are ignored.
+func ReadRows[T any](ctx context.Context, s *SheetsService, sheetId, readRange string) ([]T, error) {
+	resp, err := s.readRange(ctx, sheetId, readRange)
+	if err != nil {
+		return nil, err
+	}
+
+	// the returned range is qualified with the sheet name, unlike readRange maybe
+	col, row := rangeStart(resp.Range)
+	return decodeRows[T](resp.Values, col, row)
+}
+
+// WriteRows writes a header row followed by rows into writeRange. Columns follow
+// the declaration order of the tagged fields of T.
+func WriteRows[T any](ctx context.Context, s *SheetsService, sheetId, writeRange string, rows []T) error {
+	values, err := EncodeRows(rows)
+	if err != nil {
+		return err
+	}
+
+	return s.ExportDataToGoogleSheetByIDAndRange(ctx, sheetId, writeRange, values)
+}
+
+// DecodeRows decodes values read from a range starting at A1, the first row being the header
+func DecodeRows[T any](values [][]interface{}) ([]T, error) {
+	return decodeRows[T](values, 0, 1)
+}
+
+func decodeRows[T any](values [][]interface{}, startCol, startRow int) ([]T, error) {
+	var zero T
+	fields, err := sheetFields(reflect.TypeOf(zero))
+	if err != nil {
+		return nil, err
+	}
+
+	result := []T{}
+	if len(values) == 0 {
+		return result, nil
+	}
+
+	// column index of every field, -1 when the header is missing
+	columns := make([]int, len(fields))
+	for i, f := range fields {
+		columns[i] = -1
+		for c, h := range values[0] {
+			if strings.EqualFold(strings.TrimSpace(fmt.Sprint(h)), f.header) {
+				columns[i] = c
+				break
+			}
+		}
+	}
+
+	var errs DecodeErrors
+	for r, row := range values[1:] {
+		var item T
+		v := reflect.ValueOf(&item).Elem()
+		ok := true
+
+		for i, f := range fields {
+			c := columns[i]
+			if c < 0 || c >= len(row) {
+				continue
+			}
+
+			if err := setCell(v.Field(f.index), row[c], f.layout); err != nil {
+				errs = append(errs, &CellError{
+					Row:    startRow + 1 + r,
+					Column: ColumnLetter(startCol + c),
+					Header: f.header,
+					Value:  row[c],
+					Err:    err,
+				})
+				ok = false
+			}
+		}
+
+		if ok {
+			result = append(result, item)
+		}
+	}
+
+	if len(errs) > 0 {
+		return result, errs
+	}
+
+	return result, nil
+}
+
+// setCell converts the cell value into the field type. Empty cells leave the zero value.
+func setCell(field reflect.Value, cell interface{}, layout string) error {
+	if s, ok := cell.(string); ok {
+		cell = strings.TrimSpace(s)
+	}
+
+	if cell == nil || cell == "" {
+		return nil
+	}
+
+	if field.Kind() == reflect.Ptr {
+		ptr := reflect.New(field.Type().Elem())
+		if err := setCell(ptr.Elem(), cell, layout); err != nil {
+			return err
+		}
+		field.Set(ptr)
+		return nil
+	}
+
+	if field.Type() == timeType {
+		t, err := parseTime(cell, layout)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(cast.ToString(cell))
+	case reflect.Bool:
+		b, err := parseBool(cell)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f, err := cast.ToFloat64E(cleanNumber(cell))
+		if err != nil {
+			return err
+		}
+		if f != float64(int64(f)) {
+			return fmt.Errorf("%v is not an integer", f)
+		}
+		if field.OverflowInt(int64(f)) {
+			return fmt.Errorf("%v overflows %s", f, field.Type())
+		}
+		field.SetInt(int64(f))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := cast.ToUint64E(cleanNumber(cell))
+		if err != nil {
+			return err
+		}
+		if field.OverflowUint(i) {
+			return fmt.Errorf("%v overflows %s", i, field.Type())
+		}
+		field.SetUint(i)
+	case reflect.Float32, reflect.Float64:
+		f, err := cast.ToFloat64E(cleanNumber(cell))
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+
+	return nil
+}
+
+// cleanNumber strips currency and thousand separators from formatted sheet numbers
+func cleanNumber(cell interface{}) interface{} {
+	s, ok := cell.(string)
+	if !ok {
+		return cell
+	}
+
+	return strings.NewReplacer(",", "", "$", "", " ", "").Replace(s)
+}
+
+func parseBool(cell interface{}) (bool, error) {
+	switch strings.ToLower(cast.ToString(cell)) {
+	case "yes", "y":
+		return true, nil
+	case "no", "n":
+		return false, nil
+	}
+
+	return cast.ToBoolE(cell)
+}
+
+func parseTime(cell interface{}, layout string) (time.Time, error) {
+	if layout != "" {
+		return time.Parse(layout, cast.ToString(cell))
+	}
+
+	return cast.ToTimeE(cell)
+}
+
+// EncodeRows converts rows into sheet values, starting with a header row
+func EncodeRows[T any](rows []T) ([][]interface{}, error) {
+	var zero T
+	fields, err := sheetFields(reflect.TypeOf(zero))
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]interface{}, 0, len(fields))
+	for _, f := range fields {
+		header = append(header, f.header)
+	}
+
+	values := [][]interface{}{header}
+	for _, row := range rows {
+		values = append(values, encodeRow(reflect.ValueOf(row), fields))
+	}
+
+	return values, nil
+}
+
+func encodeRow(v reflect.Value, fields []sheetField) []interface{} {
+	cells := make([]interface{}, 0, len(fields))
+	for _, f := range fields {
+		cells = append(cells, encodeCell(v.Field(f.index), f.layout))
+	}
+	return cells
+}
+
+func encodeCell(field reflect.Value, layout string) interface{} {
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return ""
+		}
+		field = field.Elem()
+	}
+
+	if field.Type() == timeType {
+		t := field.Interface().(time.Time)
+		if t.IsZero() {
+			return ""
+		}
+		if layout == "" {
+			layout = DEFAULT_TIME_LAYOUT
+		}
+		return t.Format(layout)
+	}
+
+	return field.Interface()
+}
+
+// ColumnLetter returns the sheet column letter of the 0 based column index, e.g. 0 => A, 27 => AB
+func ColumnLetter(index int) string {
+	letters := ""
+	for index >= 0 {
+		letters = string(rune('A'+index%26)) + letters
+		index = index/26 - 1
+	}
+	return letters
+}
+
+// columnIndex returns the 0 based index of the column letters, e.g. AB => 27
+func columnIndex(letters string) int {
+	index := 0
+	for _, l := range strings.ToUpper(letters) {
+		index = index*26 + int(l-'A') + 1
+	}
+	return index - 1
+}
+
+// rangeStart returns the 0 based column and 1 based row of the top left cell of a sheet
+// qualified A1 range, such as the range returned by the API. A bare range is ambiguous,
+// "Jan" may be a tab or a cell, and starts at A1.
+func rangeStart(a1Range string) (int, int) {
+	_, cells, ok := splitRange(a1Range)
+
+	m := cellRefRegex.FindStringSubmatch(cells)
+	if !ok || m == nil {
+		return 0, 1
+	}
+
+	col, row := 0, 1
+	if m[1] != "" {
+		col = columnIndex(m[1])
+	}
+	if m[2] != "" {
+		row, _ = strconv.Atoi(m[2])
+	}
+
+	return col, row
+}
+
+// splitRange splits a sheet qualified A1 range into its sheet name, quoted as written, and
+// its cells, e.g. 'Q1 Data'!A2:C => 'Q1 Data', A2:C. ok is false for a range without sheet name.
+func splitRange(a1Range string) (string, string, bool) {
+	i := strings.LastIndex(a1Range, "!")
+	if i < 0 {
+		return "", "", false
+	}
+	return a1Range[:i], a1Range[i+1:], true
+}
@@ -0,0 +1,136 @@
+package google_sheets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testPharmacy struct {
+	NPI       string     `sheet:"NPI"`
+	Name      string     `sheet:"Pharmacy Name"`
+	Active    bool       `sheet:"Active"`
+	Copay     float64    `sheet:"Copay"`
+	Fills     int        `sheet:"Fills"`
+	StartDate time.Time  `sheet:"Start Date,layout=01/02/2006"`
+	EndDate   *time.Time `sheet:"End Date,layout=01/02/2006"`
+	Notes     string
+}
+
+func TestDecodeRows(t *testing.T) {
+	values := [][]interface{}{
+		{"Pharmacy Name", " npi ", "Active", "Copay", "Fills", "Start Date", "End Date", "Ignored"},
+		{"Phil Pharmacy", "1234567890", "Yes", "$1,250.50", "3", "03/01/2024", "", "x"},
+		{"Bad Row", "0987654321", "maybe", "10", "3.5", "03/01/2024"},
+		{"Short Row", "1111111111"},
+	}
+
+	rows, err := DecodeRows[testPharmacy](values)
+
+	var decodeErrs DecodeErrors
+	if !errors.As(err, &decodeErrs) {
+		t.Fatalf("expected DecodeErrors, got %v", err)
+	}
+
+	if len(decodeErrs) != 2 {
+		t.Fatalf("expected 2 cell errors, got %v", decodeErrs)
+	}
+	if decodeErrs[0].Row != 3 || decodeErrs[0].Column != "C" || decodeErrs[0].Header != "Active" {
+		t.Errorf("unexpected first error %+v", decodeErrs[0])
+	}
+	if decodeErrs[1].Row != 3 || decodeErrs[1].Column != "E" {
+		t.Errorf("unexpected second error %+v", decodeErrs[1])
+	}
+
+	expected := []testPharmacy{
+		{
+			NPI:       "1234567890",
+			Name:      "Phil Pharmacy",
+			Active:    true,
+			Copay:     1250.50,
+			Fills:     3,
+			StartDate: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{NPI: "1111111111", Name: "Short Row"},
+	}
+
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("expected %+v, got %+v", expected, rows)
+	}
+}
+
+func TestEncodeRows(t *testing.T) {
+	end := time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)
+	values, err := EncodeRows([]testPharmacy{
+		{NPI: "1234567890", Name: "Phil Pharmacy", Active: true, Copay: 12.5, Fills: 2, StartDate: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), EndDate: &end},
+		{NPI: "0987654321"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := [][]interface{}{
+		{"NPI", "Pharmacy Name", "Active", "Copay", "Fills", "Start Date", "End Date"},
+		{"1234567890", "Phil Pharmacy", true, 12.5, 2, "03/01/2024", "12/31/2024"},
+		{"0987654321", "", false, float64(0), 0, "", ""},
+	}
+
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %v, got %v", expected, values)
+	}
+}
+
+func TestReadRows_CellCoordinatesFollowRange(t *testing.T) {
+	var returned string
+	srv := newTestSheetsService(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, map[string]interface{}{
+			"range":  returned,
+			"values": [][]interface{}{{"NPI", "Fills"}, {"1234567890", "many"}},
+		})
+	})
+
+	// coordinates follow the sheet qualified range returned by the API, a bare range is ambiguous
+	tests := []struct {
+		readRange string
+		returned  string
+		cell      string
+	}{
+		{"Pharmacies!C5:D", "Pharmacies!C5:D1000", "D6"},
+		{"C5:D", "Sheet1!C5:D1000", "D6"},
+		{"Sheet1", "Sheet1!A1:Z1000", "B2"},
+		{"Q1 Data", "'Q1 Data'!A1:Z1000", "B2"},
+		{"Jan", "Jan!A1:Z1000", "B2"},
+		{"'Q1 Data'!B3:C", "'Q1 Data'!B3:C1000", "C4"},
+	}
+
+	for _, test := range tests {
+		returned = test.returned
+		_, err := ReadRows[testPharmacy](context.Background(), srv, "sheet-id", test.readRange)
+
+		var decodeErrs DecodeErrors
+		if !errors.As(err, &decodeErrs) || len(decodeErrs) != 1 {
+			t.Fatalf("%s: expected one decode error, got %v", test.readRange, err)
+		}
+
+		if got := fmt.Sprintf("%s%d", decodeErrs[0].Column, decodeErrs[0].Row); got != test.cell {
+			t.Errorf("%s: expected error at %s, got %s", test.readRange, test.cell, got)
+		}
+	}
+}
+
+func TestColumnLetter(t *testing.T) {
+	tests := map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"}
+
+	for index, letter := range tests {
+		if got := ColumnLetter(index); got != letter {
+			t.Errorf("ColumnLetter(%d) = %s, want %s", index, got, letter)
+		}
+		if got := columnIndex(letter); got != index {
+			t.Errorf("columnIndex(%s) = %d, want %d", letter, got, index)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package google_sheets
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"time"
+
+	drivev2 "google.golang.org/api/drive/v2"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+// SheetsService holds authenticated sheets and drive clients built once from
+// GoogleCreds. It is safe for concurrent use and should be reused across calls.
+type SheetsService struct {
+	Sheets *sheets.Service
+	Drive  *drive.Service
+}
+
+// NewSheetsService authenticates with the given credentials and builds the sheets and drive clients.
+// Requests are rate limited per project and retried on quota errors, see NewRetryTransport.
+// Extra options are applied last, e.g. option.WithEndpoint and option.WithHTTPClient
+// point the service at a local fake in tests.
+func NewSheetsService(ctx context.Context, gc *GoogleCreds, opts ...option.ClientOption) (*SheetsService, error) {
+	client, err := gc.GetClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	client.Transport = NewRetryTransport(client.Transport, gc.ProjectID)
+	opts = append([]option.ClientOption{option.WithHTTPClient(client)}, opts...)
+
+	sheetsSrv, err := sheets.NewService(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	driveSrv, err := drive.NewService(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SheetsService{Sheets: sheetsSrv, Drive: driveSrv}, nil
+}
+
+// ExportCSVToSheet takes the csvData to create a google sheet in the drive
+func (s *SheetsService) ExportCSVToSheet(ctx context.Context, namePrefix, csvData string, driveFolderId string, sheetTitle string) (string, error) {
+	r := csv.NewReader(strings.NewReader(csvData))
+	rows, err := r.ReadAll()
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := s.CreateSheetFile(ctx, driveFolderId, sheetTitle)
+	if err != nil {
+		return "", err
+	}
+
+	var vr sheets.ValueRange
+	for _, cols := range rows {
+
+		var v []interface{}
+		for _, col := range cols {
+			v = append(v, col)
+		}
+		vr.Values = append(vr.Values, v)
+	}
+
+	//Update the sheet with the csv
+	_, err = s.Sheets.Spreadsheets.Values.Update(resp.Id, DEFAULT_WRITE_RANGE, &vr).ValueInputOption("RAW").Context(ctx).Do()
+	if err != nil {
+		return "", apiError(err)
+	}
+
+	return resp.WebViewLink, nil
+}
+
+// CreateGoogleSheetInDrive creates a blank spreadsheet in the given drive folder.
+// The file is returned in its Drive v2 form for existing callers, use CreateSheetFile for the v3 file.
+func (s *SheetsService) CreateGoogleSheetInDrive(ctx context.Context, namePrefix string, driveFolderId string, title string) (*drivev2.File, error) {
+	f, err := s.CreateSheetFile(ctx, driveFolderId, title)
+	if err != nil {
+		return nil, err
+	}
+
+	v2 := &drivev2.File{
+		Id:            f.Id,
+		Title:         f.Name,
+		Description:   f.Description,
+		MimeType:      f.MimeType,
+		AlternateLink: f.WebViewLink,
+		CreatedDate:   f.CreatedTime,
+		ModifiedDate:  f.ModifiedTime,
+	}
+	for _, p := range f.Parents {
+		v2.Parents = append(v2.Parents, &drivev2.ParentReference{Id: p})
+	}
+
+	return v2, nil
+}
+
+// CreateSheetFile creates a blank spreadsheet in the given drive folder, shared drives included
+func (s *SheetsService) CreateSheetFile(ctx context.Context, driveFolderId string, title string) (*drive.File, error) {
+	fi := &drive.File{Name: title, Description: SHEET_DESC, MimeType: SHEET_MIMETYPE, Parents: []string{driveFolderId}}
+
+	f, err := s.Drive.Files.Create(fi).
+		Fields(DRIVE_FILE_FIELDS).
+		SupportsAllDrives(true).
+		Context(ctx).
+		Do()
+	return f, apiError(err)
+}
+
+// ReadDataFromGoogleSpreadSheetByIDAndRange reads the values of the given range
+func (s *SheetsService) ReadDataFromGoogleSpreadSheetByIDAndRange(ctx context.Context, sheetId, readRange string) ([][]interface{}, error) {
+	resp, err := s.readRange(ctx, sheetId, readRange)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Values, nil
+}
+
+// readRange reads the values of readRange along with the range they start at, qualified with the sheet name
+func (s *SheetsService) readRange(ctx context.Context, sheetId, readRange string) (*sheets.ValueRange, error) {
+	resp, err := s.Sheets.Spreadsheets.Values.Get(sheetId, readRange).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to retrieve data from sheet: %w", apiError(err))
+	}
+
+	return resp, nil
+}
+
+// ReadMetaDataFromGoogleSpreadSheetByID reads the resource key and link share metadata of the spreadsheet
+func (s *SheetsService) ReadMetaDataFromGoogleSpreadSheetByID(ctx context.Context, sheetId string) (*FileMetaData, error) {
+	f, err := s.Drive.Files.Get(sheetId).Fields("resourceKey", "linkShareMetadata").SupportsAllDrives(true).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve meta data from sheet: %w", apiError(err))
+	}
+
+	metaData := &FileMetaData{ResourceKey: f.ResourceKey}
+	if f.LinkShareMetadata != nil {
+		metaData.LinkShareMetaData = linkShareMetaData{
+			SecurityUpdateEligible: f.LinkShareMetadata.SecurityUpdateEligible,
+			SecurityUpdateEnabled:  f.LinkShareMetadata.SecurityUpdateEnabled,
+		}
+	}
+
+	return metaData, nil
+}
+
+// ExportDataToGoogleSheetByIDAndRange takes the spreadsheet rows update the specified google sheet
+func (s *SheetsService) ExportDataToGoogleSheetByIDAndRange(ctx context.Context, sheetId, writeRange string, rows [][]interface{}) error {
+	return s.updateValues(ctx, sheetId, writeRange, rows, "RAW")
+}
+
+// ExportDataToGoogleSheetByIDAndRangeParsedAsUserTyped takes the spreadsheet rows update the specified google sheet and parse as user typed
+func (s *SheetsService) ExportDataToGoogleSheetByIDAndRangeParsedAsUserTyped(ctx context.Context, sheetId, writeRange string, rows [][]interface{}) error {
+	return s.updateValues(ctx, sheetId, writeRange, rows, "USER_ENTERED")
+}
+
+func (s *SheetsService) updateValues(ctx context.Context, sheetId, writeRange string, rows [][]interface{}, valueInputOption string) error {
+	vr := sheets.ValueRange{Values: rows}
+
+	_, err := s.Sheets.Spreadsheets.Values.Update(sheetId, writeRange, &vr).ValueInputOption(valueInputOption).Context(ctx).Do()
+	return apiError(err)
+}
+
+// ClearDataOfGoogleSheetByIDAndRange clears column data of the specified range
+func (s *SheetsService) ClearDataOfGoogleSheetByIDAndRange(ctx context.Context, sheetId string, clearRanges []string) error {
+	cr := sheets.BatchClearValuesRequest{Ranges: clearRanges}
+
+	_, err := s.Sheets.Spreadsheets.Values.BatchClear(sheetId, &cr).Context(ctx).Do()
+	return apiError(err)
+}
+
+// GetModifiedDate gets the date when the google document was modified
+func (s *SheetsService) GetModifiedDate(ctx context.Context, fileID string) (*time.Time, error) {
+	f, err := s.Drive.Files.Get(fileID).Fields("modifiedTime").SupportsAllDrives(true).Context(ctx).Do()
+	if err != nil {
+		return nil, apiError(err)
+	}
+
+	date, err := time.Parse(time.RFC3339, f.ModifiedTime)
+	if err != nil {
+		return nil, err
+	}
+
+	return &date, nil
+}
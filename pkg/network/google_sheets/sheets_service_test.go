@@ -0,0 +1,101 @@
+package google_sheets
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/option"
+)
+
+// newTestSheetsService returns a service pointed at a local fake serving handler.
+// Sheets requests arrive under /v4/, drive requests under /files.
+func newTestSheetsService(t *testing.T, handler http.HandlerFunc) *SheetsService {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	gc := &GoogleCreds{Type: "service_account", ClientEmail: "test@phil.us", TokenURI: server.URL + "/token"}
+
+	srv, err := NewSheetsService(context.Background(), gc, option.WithEndpoint(server.URL+"/"), option.WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+
+	return srv
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, v interface{}) {
+	t.Helper()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		t.Fatalf("failed to write response: %v", err)
+	}
+}
+
+func TestSheetsService_ReadData(t *testing.T) {
+	srv := newTestSheetsService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v4/spreadsheets/sheet-id/values/Sheet1!A1:B2" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		writeJSON(t, w, map[string]interface{}{
+			"range":  "Sheet1!A1:B2",
+			"values": [][]interface{}{{"ndc", "name"}, {"0002-1433-80", "Trulicity"}},
+		})
+	})
+
+	rows, err := srv.ReadDataFromGoogleSpreadSheetByIDAndRange(context.Background(), "sheet-id", "Sheet1!A1:B2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := [][]interface{}{{"ndc", "name"}, {"0002-1433-80", "Trulicity"}}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("expected %v, got %v", expected, rows)
+	}
+}
+
+func TestSheetsService_ExportData(t *testing.T) {
+	var body map[string]interface{}
+	srv := newTestSheetsService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Query().Get("valueInputOption") != "USER_ENTERED" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		b, _ := io.ReadAll(r.Body)
+		json.Unmarshal(b, &body)
+		writeJSON(t, w, map[string]interface{}{})
+	})
+
+	err := srv.ExportDataToGoogleSheetByIDAndRangeParsedAsUserTyped(context.Background(), "sheet-id", "Sheet1", [][]interface{}{{"a", 1}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(body["values"], []interface{}{[]interface{}{"a", float64(1)}}) {
+		t.Errorf("unexpected body %v", body)
+	}
+}
+
+func TestSheetsService_GetModifiedDate(t *testing.T) {
+	srv := newTestSheetsService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/files/file-id" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		writeJSON(t, w, map[string]interface{}{"id": "file-id", "modifiedTime": "2024-03-01T10:00:00Z"})
+	})
+
+	date, err := srv.GetModifiedDate(context.Background(), "file-id")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if date.Format("2006-01-02T15:04:05Z07:00") != "2024-03-01T10:00:00Z" {
+		t.Errorf("unexpected date %v", date)
+	}
+}
@@ -0,0 +1,292 @@
+package google_sheets
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phil-inc/pcommon/pkg/internal/watch"
+	logger "github.com/phil-inc/plog-ng/pkg/core"
+)
+
+const (
+	// DefaultSyncPollInterval is used when SheetSyncConfig.PollInterval is not set
+	DefaultSyncPollInterval = 5 * time.Minute
+)
+
+// Snapshot is the content of a synced range at the time it was last handled
+type Snapshot struct {
+	ModifiedTime time.Time  `json:"modified_time"`
+	Header       []string   `json:"header"`
+	Rows         [][]string `json:"rows"`
+}
+
+// SyncRow maps the headers of a synced range to the values of one row
+type SyncRow map[string]string
+
+// RowChange is a row whose key is unchanged but at least one value differs
+type RowChange struct {
+	Key string
+	Old SyncRow
+	New SyncRow
+}
+
+// SheetChanges lists the differences between two snapshots, in sheet order.
+// On the first sync every row is Added.
+type SheetChanges struct {
+	Added   []SyncRow
+	Removed []SyncRow
+	Changed []RowChange
+}
+
+// Empty reports whether no row was added, removed or changed
+func (c SheetChanges) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Changed) == 0
+}
+
+// SnapshotStore keeps the last handled snapshot of every synced range so that
+// a restart does not report the whole sheet as added again
+type SnapshotStore interface {
+	// Get returns the snapshot stored under key, if any
+	Get(ctx context.Context, key string) (*Snapshot, error)
+	// Put replaces the snapshot stored under key
+	Put(ctx context.Context, key string, snapshot *Snapshot) error
+}
+
+// SheetSyncHandler processes the changes of a synced range. When an error is returned
+// the snapshot is not updated and the same changes are reported again on the next poll.
+type SheetSyncHandler func(ctx context.Context, changes SheetChanges) error
+
+// SheetSyncConfig configures SheetSync
+type SheetSyncConfig struct {
+	// SheetID is the spreadsheet to poll
+	SheetID string
+	// Range is read when the spreadsheet changed, its first row is the header
+	Range string
+	// KeyColumn is the header of the column identifying rows, e.g. "NDC".
+	// Rows without a key are ignored, and the last row wins when a key is repeated.
+	KeyColumn string
+	// PollInterval is the time between two modification checks, defaults to DefaultSyncPollInterval
+	PollInterval time.Duration
+	// Store keeps the last snapshot, defaults to an in memory store
+	Store SnapshotStore
+	// OnError is called with errors that do not stop Run, defaults to logging them
+	OnError func(err error)
+}
+
+// SheetSync polls the modification time of a spreadsheet and, when it changed,
+// reads a range and hands the rows added, removed or changed since the last snapshot to a handler
+type SheetSync struct {
+	srv     *SheetsService
+	config  SheetSyncConfig
+	handler SheetSyncHandler
+}
+
+// NewSheetSync creates a sync of config.Range using an existing service
+func NewSheetSync(srv *SheetsService, config SheetSyncConfig, handler SheetSyncHandler) *SheetSync {
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultSyncPollInterval
+	}
+
+	if config.Store == nil {
+		config.Store = NewMemorySnapshotStore()
+	}
+
+	if config.OnError == nil {
+		config.OnError = func(err error) {
+			logger.Errorf("[SHEETS][SYNC] %s", err.Error())
+		}
+	}
+
+	return &SheetSync{srv: srv, config: config, handler: handler}
+}
+
+// Run polls the spreadsheet until ctx is cancelled
+func (s *SheetSync) Run(ctx context.Context) error {
+	return watch.Run(ctx, s.config.PollInterval, s.Poll, s.config.OnError)
+}
+
+// Poll checks the modification time once and syncs the range if the spreadsheet changed
+func (s *SheetSync) Poll(ctx context.Context) error {
+	modified, err := s.srv.GetModifiedDate(ctx, s.config.SheetID)
+	if err != nil {
+		return fmt.Errorf("unable to get modified date of %s: %w", s.config.SheetID, err)
+	}
+
+	key := s.config.SheetID + "|" + s.config.Range
+	previous, err := s.config.Store.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	if previous != nil && !modified.After(previous.ModifiedTime) {
+		return nil
+	}
+
+	values, err := s.srv.ReadDataFromGoogleSpreadSheetByIDAndRange(ctx, s.config.SheetID, s.config.Range)
+	if err != nil {
+		return err
+	}
+
+	current := newSnapshot(*modified, values)
+	changes, err := DiffSnapshots(previous, current, s.config.KeyColumn)
+	if err != nil {
+		return err
+	}
+
+	// formatting only edits change the modification time without changing values
+	if !changes.Empty() {
+		if err := s.handler(ctx, changes); err != nil {
+			return err
+		}
+	}
+
+	return s.config.Store.Put(ctx, key, current)
+}
+
+func newSnapshot(modified time.Time, values [][]interface{}) *Snapshot {
+	snapshot := &Snapshot{ModifiedTime: modified, Header: []string{}, Rows: [][]string{}}
+	if len(values) == 0 {
+		return snapshot
+	}
+
+	for _, h := range values[0] {
+		snapshot.Header = append(snapshot.Header, strings.TrimSpace(fmt.Sprint(h)))
+	}
+
+	for _, r := range values[1:] {
+		row := make([]string, len(r))
+		for i, v := range r {
+			row[i] = fmt.Sprint(v)
+		}
+		snapshot.Rows = append(snapshot.Rows, row)
+	}
+
+	return snapshot
+}
+
+// DiffSnapshots compares two snapshots row by row using keyColumn. Rows are compared
+// by header name, so reordering columns is not reported as a change. previous may be nil.
+func DiffSnapshots(previous, current *Snapshot, keyColumn string) (SheetChanges, error) {
+	changes := SheetChanges{}
+
+	currentKeys, currentRows, err := current.rowsByKey(keyColumn)
+	if err != nil {
+		return changes, err
+	}
+
+	var previousKeys []string
+	previousRows := map[string]SyncRow{}
+	if previous != nil && len(previous.Header) > 0 {
+		previousKeys, previousRows, err = previous.rowsByKey(keyColumn)
+		if err != nil {
+			return changes, err
+		}
+	}
+
+	for _, k := range currentKeys {
+		old, ok := previousRows[k]
+		if !ok {
+			changes.Added = append(changes.Added, currentRows[k])
+		} else if !reflect.DeepEqual(old, currentRows[k]) {
+			changes.Changed = append(changes.Changed, RowChange{Key: k, Old: old, New: currentRows[k]})
+		}
+	}
+
+	for _, k := range previousKeys {
+		if _, ok := currentRows[k]; !ok {
+			changes.Removed = append(changes.Removed, previousRows[k])
+		}
+	}
+
+	return changes, nil
+}
+
+// rowsByKey returns the keys in sheet order and the rows by key
+func (s *Snapshot) rowsByKey(keyColumn string) ([]string, map[string]SyncRow, error) {
+	rows := map[string]SyncRow{}
+	if s == nil || len(s.Header) == 0 {
+		return nil, rows, nil
+	}
+
+	keyIndex := -1
+	for i, h := range s.Header {
+		if normalizeHeader(h) == normalizeHeader(keyColumn) {
+			keyIndex = i
+			break
+		}
+	}
+	if keyIndex < 0 {
+		return nil, nil, fmt.Errorf("key column %q not found in sheet header", keyColumn)
+	}
+
+	var keys []string
+	for _, r := range s.Rows {
+		if keyIndex >= len(r) || strings.TrimSpace(r[keyIndex]) == "" {
+			continue
+		}
+
+		row := SyncRow{}
+		for i, h := range s.Header {
+			row[h] = ""
+			if i < len(r) {
+				row[h] = r[i]
+			}
+		}
+
+		k := strings.TrimSpace(r[keyIndex])
+		if _, ok := rows[k]; !ok {
+			keys = append(keys, k)
+		}
+		rows[k] = row
+	}
+
+	return keys, rows, nil
+}
+
+// MemorySnapshotStore keeps snapshots in memory, they are lost on restart
+type MemorySnapshotStore struct {
+	snapshots map[string]*Snapshot
+	mu        sync.Mutex
+}
+
+func NewMemorySnapshotStore() *MemorySnapshotStore {
+	return &MemorySnapshotStore{snapshots: map[string]*Snapshot{}}
+}
+
+func (s *MemorySnapshotStore) Get(ctx context.Context, key string) (*Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.snapshots[key], nil
+}
+
+func (s *MemorySnapshotStore) Put(ctx context.Context, key string, snapshot *Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.snapshots[key] = snapshot
+	return nil
+}
+
+// FileSnapshotStore persists snapshots as JSON in a local file
+type FileSnapshotStore struct {
+	file *watch.JSONFile[*Snapshot]
+}
+
+// NewFileSnapshotStore returns a store persisted to path. The file is created on first write.
+func NewFileSnapshotStore(path string) *FileSnapshotStore {
+	return &FileSnapshotStore{file: watch.NewJSONFile[*Snapshot](path)}
+}
+
+func (s *FileSnapshotStore) Get(ctx context.Context, key string) (*Snapshot, error) {
+	snapshot, _, err := s.file.Get(key)
+	return snapshot, err
+}
+
+func (s *FileSnapshotStore) Put(ctx context.Context, key string, snapshot *Snapshot) error {
+	return s.file.Put(key, snapshot)
+}
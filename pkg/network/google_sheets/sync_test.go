@@ -0,0 +1,127 @@
+package google_sheets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSyncSheet serves a spreadsheet whose modified time and values can be changed between polls
+type fakeSyncSheet struct {
+	modified string
+	values   [][]interface{}
+	reads    int
+}
+
+func (f *fakeSyncSheet) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/files/") {
+			writeJSON(t, w, map[string]interface{}{"modifiedTime": f.modified})
+			return
+		}
+		f.reads++
+		writeJSON(t, w, map[string]interface{}{"values": f.values})
+	}
+}
+
+func TestSheetSync_Poll(t *testing.T) {
+	sheet := &fakeSyncSheet{
+		modified: "2024-03-01T10:00:00Z",
+		values: [][]interface{}{
+			{"NDC", "Name"},
+			{"0002-1433-80", "Trulicity"},
+			{"0169-4060-13", "Ozempic"},
+		},
+	}
+	srv := newTestSheetsService(t, sheet.handler(t))
+
+	var received []SheetChanges
+	sync := NewSheetSync(srv, SheetSyncConfig{
+		SheetID:   "sheet-id",
+		Range:     "NDCs!A1:B",
+		KeyColumn: "ndc",
+		Store:     NewFileSnapshotStore(filepath.Join(t.TempDir(), "snapshots.json")),
+	}, func(ctx context.Context, changes SheetChanges) error {
+		received = append(received, changes)
+		return nil
+	})
+
+	ctx := context.Background()
+	if err := sync.Poll(ctx); err != nil {
+		t.Fatalf("unexpected poll error: %v", err)
+	}
+	if len(received) != 1 || len(received[0].Added) != 2 {
+		t.Fatalf("expected every row to be added on first sync, got %+v", received)
+	}
+
+	// unchanged modified time does not read the sheet
+	if err := sync.Poll(ctx); err != nil {
+		t.Fatalf("unexpected poll error: %v", err)
+	}
+	if sheet.reads != 1 || len(received) != 1 {
+		t.Fatalf("expected no read when the sheet did not change, got %d reads", sheet.reads)
+	}
+
+	sheet.modified = "2024-03-02T10:00:00Z"
+	sheet.values = [][]interface{}{
+		{"Name", "NDC"},
+		{"Trulicity 1.5mg", "0002-1433-80"},
+		{"Mounjaro", "0002-1506-80"},
+	}
+	if err := sync.Poll(ctx); err != nil {
+		t.Fatalf("unexpected poll error: %v", err)
+	}
+
+	expected := SheetChanges{
+		Added:   []SyncRow{{"NDC": "0002-1506-80", "Name": "Mounjaro"}},
+		Removed: []SyncRow{{"NDC": "0169-4060-13", "Name": "Ozempic"}},
+		Changed: []RowChange{{
+			Key: "0002-1433-80",
+			Old: SyncRow{"NDC": "0002-1433-80", "Name": "Trulicity"},
+			New: SyncRow{"NDC": "0002-1433-80", "Name": "Trulicity 1.5mg"},
+		}},
+	}
+	if len(received) != 2 || !reflect.DeepEqual(received[1], expected) {
+		t.Errorf("expected %+v, got %+v", expected, received[1:])
+	}
+}
+
+func TestSheetSync_HandlerErrorRetries(t *testing.T) {
+	sheet := &fakeSyncSheet{
+		modified: "2024-03-01T10:00:00Z",
+		values:   [][]interface{}{{"NDC"}, {"0002-1433-80"}},
+	}
+	srv := newTestSheetsService(t, sheet.handler(t))
+
+	calls := 0
+	sync := NewSheetSync(srv, SheetSyncConfig{SheetID: "sheet-id", Range: "NDCs", KeyColumn: "NDC"}, func(ctx context.Context, changes SheetChanges) error {
+		calls++
+		if calls == 1 {
+			return errors.New("database down")
+		}
+		return nil
+	})
+
+	if err := sync.Poll(context.Background()); err == nil {
+		t.Fatal("expected handler error")
+	}
+	if err := sync.Poll(context.Background()); err != nil {
+		t.Fatalf("unexpected poll error: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected the changes to be handled again, got %d calls", calls)
+	}
+}
+
+func TestDiffSnapshots_MissingKeyColumn(t *testing.T) {
+	current := newSnapshot(time.Now(), [][]interface{}{{"Name"}, {"Trulicity"}})
+
+	if _, err := DiffSnapshots(nil, current, "NDC"); err == nil {
+		t.Error("expected missing key column error")
+	}
+}
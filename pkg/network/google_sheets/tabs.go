@@ -0,0 +1,103 @@
+package google_sheets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// ErrTabNotFound is returned when a spreadsheet has no tab with the requested title
+var ErrTabNotFound = errors.New("tab not found")
+
+// Tab describes a sheet (tab) of a spreadsheet
+type Tab struct {
+	ID    int64
+	Title string
+	Index int64
+}
+
+// ListTabs returns the tabs of the spreadsheet in display order
+func (s *SheetsService) ListTabs(ctx context.Context, sheetId string) ([]Tab, error) {
+	resp, err := s.Sheets.Spreadsheets.Get(sheetId).Fields("sheets.properties").Context(ctx).Do()
+	if err != nil {
+		return nil, apiError(err)
+	}
+
+	tabs := make([]Tab, 0, len(resp.Sheets))
+	for _, sh := range resp.Sheets {
+		if sh.Properties == nil {
+			continue
+		}
+		tabs = append(tabs, Tab{ID: sh.Properties.SheetId, Title: sh.Properties.Title, Index: sh.Properties.Index})
+	}
+
+	return tabs, nil
+}
+
+// TabID returns the id of the tab with the given title, used by the tab and formatting requests
+func (s *SheetsService) TabID(ctx context.Context, sheetId, title string) (int64, error) {
+	tabs, err := s.ListTabs(ctx, sheetId)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, tab := range tabs {
+		if tab.Title == title {
+			return tab.ID, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: %q", ErrTabNotFound, title)
+}
+
+// AddTab adds a tab at the end of the spreadsheet and returns its id
+func (s *SheetsService) AddTab(ctx context.Context, sheetId, title string) (int64, error) {
+	resp, err := s.ApplyRequests(ctx, sheetId, &sheets.Request{
+		AddSheet: &sheets.AddSheetRequest{Properties: &sheets.SheetProperties{Title: title}},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return resp.Replies[0].AddSheet.Properties.SheetId, nil
+}
+
+// RenameTab changes the title of a tab
+func (s *SheetsService) RenameTab(ctx context.Context, sheetId string, tabId int64, title string) error {
+	_, err := s.ApplyRequests(ctx, sheetId, &sheets.Request{
+		UpdateSheetProperties: &sheets.UpdateSheetPropertiesRequest{
+			Properties: &sheets.SheetProperties{SheetId: tabId, Title: title, ForceSendFields: []string{"SheetId"}},
+			Fields:     "title",
+		},
+	})
+	return err
+}
+
+// DeleteTab removes a tab and its data
+func (s *SheetsService) DeleteTab(ctx context.Context, sheetId string, tabId int64) error {
+	_, err := s.ApplyRequests(ctx, sheetId, &sheets.Request{
+		DeleteSheet: &sheets.DeleteSheetRequest{SheetId: tabId, ForceSendFields: []string{"SheetId"}},
+	})
+	return err
+}
+
+// DuplicateTab copies a tab, data and formatting included, and returns the id of the copy
+func (s *SheetsService) DuplicateTab(ctx context.Context, sheetId string, tabId int64, title string) (int64, error) {
+	resp, err := s.ApplyRequests(ctx, sheetId, &sheets.Request{
+		DuplicateSheet: &sheets.DuplicateSheetRequest{SourceSheetId: tabId, NewSheetName: title, ForceSendFields: []string{"SourceSheetId"}},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return resp.Replies[0].DuplicateSheet.Properties.SheetId, nil
+}
+
+// ApplyRequests sends the requests in a single spreadsheet batch update, they are applied atomically in order
+func (s *SheetsService) ApplyRequests(ctx context.Context, sheetId string, requests ...*sheets.Request) (*sheets.BatchUpdateSpreadsheetResponse, error) {
+	req := sheets.BatchUpdateSpreadsheetRequest{Requests: requests}
+	resp, err := s.Sheets.Spreadsheets.BatchUpdate(sheetId, &req).Context(ctx).Do()
+	return resp, apiError(err)
+}
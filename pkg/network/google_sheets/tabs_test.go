@@ -0,0 +1,78 @@
+package google_sheets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestSheetsService_TabID(t *testing.T) {
+	srv := newTestSheetsService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v4/spreadsheets/sheet-id" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		writeJSON(t, w, map[string]interface{}{
+			"sheets": []interface{}{
+				map[string]interface{}{"properties": map[string]interface{}{"sheetId": 0, "title": "Sheet1"}},
+				map[string]interface{}{"properties": map[string]interface{}{"sheetId": 42, "title": "Claims", "index": 1}},
+			},
+		})
+	})
+
+	id, err := srv.TabID(context.Background(), "sheet-id", "Claims")
+	if err != nil || id != 42 {
+		t.Errorf("expected tab 42, got %d, %v", id, err)
+	}
+
+	if _, err := srv.TabID(context.Background(), "sheet-id", "Missing"); !errors.Is(err, ErrTabNotFound) {
+		t.Errorf("expected ErrTabNotFound, got %v", err)
+	}
+}
+
+func TestSheetsService_AddAndRenameTab(t *testing.T) {
+	var bodies []string
+	srv := newTestSheetsService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v4/spreadsheets/sheet-id:batchUpdate" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+
+		writeJSON(t, w, map[string]interface{}{
+			"replies": []interface{}{
+				map[string]interface{}{"addSheet": map[string]interface{}{"properties": map[string]interface{}{"sheetId": 7}}},
+			},
+		})
+	})
+
+	id, err := srv.AddTab(context.Background(), "sheet-id", "Report")
+	if err != nil || id != 7 {
+		t.Fatalf("expected tab 7, got %d, %v", id, err)
+	}
+
+	if err := srv.RenameTab(context.Background(), "sheet-id", 0, "Summary"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var rename struct {
+		Requests []struct {
+			UpdateSheetProperties struct {
+				Properties map[string]interface{} `json:"properties"`
+				Fields     string                 `json:"fields"`
+			} `json:"updateSheetProperties"`
+		} `json:"requests"`
+	}
+	json.Unmarshal([]byte(bodies[1]), &rename)
+
+	props := rename.Requests[0].UpdateSheetProperties
+	if props.Fields != "title" || props.Properties["title"] != "Summary" {
+		t.Errorf("unexpected rename request %s", bodies[1])
+	}
+	// the first tab has id 0 which must still be sent
+	if _, ok := props.Properties["sheetId"]; !ok {
+		t.Errorf("sheetId missing from %s", bodies[1])
+	}
+}
@@ -0,0 +1,293 @@
+package google_sheets
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+const (
+	VALUE_INPUT_RAW          = "RAW"
+	VALUE_INPUT_USER_ENTERED = "USER_ENTERED"
+)
+
+// MaxCellsPerRequest bounds the number of cells sent in a single write request, larger
+// writes are split into several requests to stay within the Sheets API payload limits
+var MaxCellsPerRequest = 50000
+
+// RangeValues holds the values to write starting at the top left cell of Range
+type RangeValues struct {
+	Range  string
+	Values [][]interface{}
+}
+
+// UpsertResult reports what UpsertRows changed
+type UpsertResult struct {
+	Updated  int
+	Appended int
+}
+
+// AppendRows appends rows after the last row of the table found in appendRange
+func (s *SheetsService) AppendRows(ctx context.Context, sheetId, appendRange string, rows [][]interface{}) error {
+	for _, chunk := range chunkRows(rows) {
+		vr := sheets.ValueRange{Values: chunk}
+
+		_, err := s.Sheets.Spreadsheets.Values.Append(sheetId, appendRange, &vr).
+			ValueInputOption(VALUE_INPUT_RAW).
+			InsertDataOption("INSERT_ROWS").
+			Context(ctx).
+			Do()
+		if err != nil {
+			return apiError(err)
+		}
+	}
+
+	return nil
+}
+
+// BatchUpdate writes several ranges with as few requests as possible
+func (s *SheetsService) BatchUpdate(ctx context.Context, sheetId string, data []RangeValues) error {
+	var batch []*sheets.ValueRange
+	cells := 0
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		req := sheets.BatchUpdateValuesRequest{ValueInputOption: VALUE_INPUT_RAW, Data: batch}
+		_, err := s.Sheets.Spreadsheets.Values.BatchUpdate(sheetId, &req).Context(ctx).Do()
+
+		batch, cells = nil, 0
+		return apiError(err)
+	}
+
+	for _, d := range data {
+		col, row := rangeStart(d.Range)
+		offset := 0
+
+		chunks := chunkRows(d.Values)
+		if _, _, ok := splitRange(d.Range); !ok && len(chunks) > 1 {
+			return fmt.Errorf("%w: %q must be qualified with its sheet name to be split, e.g. Sheet1!A1", ErrInvalidRange, d.Range)
+		}
+
+		for _, chunk := range chunks {
+			n := countCells(chunk)
+			if cells+n > MaxCellsPerRequest {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+
+			r := d.Range
+			if offset > 0 {
+				r = cellRange(d.Range, col, row+offset)
+			}
+
+			batch = append(batch, &sheets.ValueRange{Range: r, Values: chunk})
+			cells += n
+			offset += len(chunk)
+		}
+	}
+
+	return flush()
+}
+
+// UpsertRows updates in place the rows of sheetRange whose keyColumn value matches one
+// of rows, and appends the others below the last row. The first row of rows is a header,
+// as produced by EncodeRows, and columns are matched to the sheet header by name.
+// Only the cells of the columns of rows are written, the other sheet columns keep their
+// current value, formulas and formats.
+func (s *SheetsService) UpsertRows(ctx context.Context, sheetId, sheetRange, keyColumn string, rows [][]interface{}) (*UpsertResult, error) {
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("rows must start with a header row")
+	}
+
+	resp, err := s.readRange(ctx, sheetId, sheetRange)
+	if err != nil {
+		return nil, err
+	}
+
+	// writes go to the sheet of the returned range, sheetRange may not name it
+	existing := resp.Values
+	col, row := rangeStart(resp.Range)
+	result := &UpsertResult{}
+
+	// an empty sheet gets the header and all rows
+	if len(existing) == 0 {
+		result.Appended = len(rows) - 1
+		return result, s.BatchUpdate(ctx, sheetId, []RangeValues{{Range: cellRange(resp.Range, col, row), Values: rows}})
+	}
+
+	sheetHeader := existing[0]
+	sheetColumns := map[string]int{}
+	for i, h := range sheetHeader {
+		sheetColumns[normalizeHeader(h)] = i
+	}
+
+	// sheet column of every input column
+	mapping := make([]int, len(rows[0]))
+	inputKey := -1
+	for i, h := range rows[0] {
+		c, ok := sheetColumns[normalizeHeader(h)]
+		if !ok {
+			return nil, fmt.Errorf("column %q not found in sheet header", fmt.Sprint(h))
+		}
+		mapping[i] = c
+
+		if normalizeHeader(h) == normalizeHeader(keyColumn) {
+			inputKey = i
+		}
+	}
+
+	sheetKey, ok := sheetColumns[normalizeHeader(keyColumn)]
+	if !ok || inputKey < 0 {
+		return nil, fmt.Errorf("key column %q must be present in the sheet and in rows", keyColumn)
+	}
+
+	// sheet values by key, and their position in existing
+	positions := map[string]int{}
+	for i, r := range existing[1:] {
+		if sheetKey < len(r) {
+			positions[cellKey(r[sheetKey])] = i + 1
+		}
+	}
+
+	firstNew := len(existing)
+	updated := map[int]bool{}
+	// cells written at every position in existing, nil for the columns missing from rows
+	written := map[int][]interface{}{}
+
+	for _, input := range rows[1:] {
+		var key string
+		if inputKey < len(input) {
+			key = cellKey(input[inputKey])
+		}
+
+		pos, ok := positions[key]
+		if !ok || key == "" {
+			pos = len(existing)
+			existing = append(existing, nil)
+			if key != "" {
+				positions[key] = pos
+			}
+			result.Appended++
+		} else if pos < firstNew && !updated[pos] {
+			updated[pos] = true
+			result.Updated++
+		}
+
+		cells, ok := written[pos]
+		if !ok {
+			cells = make([]interface{}, len(sheetHeader))
+			written[pos] = cells
+		}
+		for i, v := range input {
+			cells[mapping[i]] = v
+		}
+	}
+
+	runs := columnRuns(mapping)
+
+	var updates []RangeValues
+	for pos := 1; pos < firstNew; pos++ {
+		if updated[pos] {
+			updates = append(updates, runRanges(resp.Range, col, row+pos, runs, [][]interface{}{written[pos]})...)
+		}
+	}
+
+	// appended rows are contiguous, write each run of columns as a single range
+	if len(existing) > firstNew {
+		var appended [][]interface{}
+		for pos := firstNew; pos < len(existing); pos++ {
+			appended = append(appended, written[pos])
+		}
+		updates = append(updates, runRanges(resp.Range, col, row+firstNew, runs, appended)...)
+	}
+
+	return result, s.BatchUpdate(ctx, sheetId, updates)
+}
+
+// columnRuns returns the contiguous runs [start, end) of the sheet columns
+func columnRuns(columns []int) [][2]int {
+	sorted := append([]int(nil), columns...)
+	sort.Ints(sorted)
+
+	var runs [][2]int
+	for _, c := range sorted {
+		if n := len(runs); n > 0 && c <= runs[n-1][1] {
+			runs[n-1][1] = c + 1
+			continue
+		}
+		runs = append(runs, [2]int{c, c + 1})
+	}
+
+	return runs
+}
+
+// runRanges returns the cells of every run of columns of rows, the first row being at
+// startRow. Cells left nil, for input rows shorter than the header, are skipped by the API.
+func runRanges(a1Range string, startCol, startRow int, runs [][2]int, rows [][]interface{}) []RangeValues {
+	ranges := make([]RangeValues, 0, len(runs))
+	for _, run := range runs {
+		values := make([][]interface{}, len(rows))
+		for i, r := range rows {
+			values[i] = r[run[0]:run[1]]
+		}
+		ranges = append(ranges, RangeValues{Range: cellRange(a1Range, startCol+run[0], startRow), Values: values})
+	}
+
+	return ranges
+}
+
+// chunkRows splits rows so that every chunk holds at most MaxCellsPerRequest cells
+func chunkRows(rows [][]interface{}) [][][]interface{} {
+	var chunks [][][]interface{}
+	start, cells := 0, 0
+
+	for i, r := range rows {
+		n := len(r)
+		if n == 0 {
+			n = 1
+		}
+
+		if cells+n > MaxCellsPerRequest && i > start {
+			chunks = append(chunks, rows[start:i])
+			start, cells = i, 0
+		}
+		cells += n
+	}
+
+	if start < len(rows) {
+		chunks = append(chunks, rows[start:])
+	}
+
+	return chunks
+}
+
+func countCells(rows [][]interface{}) int {
+	n := 0
+	for _, r := range rows {
+		n += len(r)
+	}
+	return n
+}
+
+// cellRange returns the A1 notation of a single cell on the sheet of the sheet qualified
+// a1Range, e.g. Sheet1!C7 or 'Q1 Data'!A2
+func cellRange(a1Range string, col, row int) string {
+	sheet, _, _ := splitRange(a1Range)
+	return sheet + "!" + ColumnLetter(col) + strconv.Itoa(row)
+}
+
+func normalizeHeader(h interface{}) string {
+	return strings.ToLower(strings.TrimSpace(fmt.Sprint(h)))
+}
+
+func cellKey(v interface{}) string {
+	return strings.TrimSpace(fmt.Sprint(v))
+}
@@ -0,0 +1,233 @@
+package google_sheets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type batchUpdateBody struct {
+	Data []struct {
+		Range  string          `json:"range"`
+		Values [][]interface{} `json:"values"`
+	} `json:"data"`
+}
+
+func TestSheetsService_AppendRows_Chunks(t *testing.T) {
+	defer func(n int) { MaxCellsPerRequest = n }(MaxCellsPerRequest)
+	MaxCellsPerRequest = 4
+
+	var appended [][][]interface{}
+	srv := newTestSheetsService(t, func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, ":append") || r.URL.Query().Get("insertDataOption") != "INSERT_ROWS" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+
+		var body struct {
+			Values [][]interface{} `json:"values"`
+		}
+		b, _ := io.ReadAll(r.Body)
+		json.Unmarshal(b, &body)
+		appended = append(appended, body.Values)
+
+		writeJSON(t, w, map[string]interface{}{})
+	})
+
+	rows := [][]interface{}{{"a", "1"}, {"b", "2"}, {"c", "3"}}
+	if err := srv.AppendRows(context.Background(), "sheet-id", "Sheet1!A:B", rows); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := [][][]interface{}{{{"a", "1"}, {"b", "2"}}, {{"c", "3"}}}
+	if !reflect.DeepEqual(appended, expected) {
+		t.Errorf("expected %v, got %v", expected, appended)
+	}
+}
+
+func TestSheetsService_BatchUpdate_SplitsLargeRanges(t *testing.T) {
+	defer func(n int) { MaxCellsPerRequest = n }(MaxCellsPerRequest)
+	MaxCellsPerRequest = 4
+
+	var ranges [][]string
+	srv := newTestSheetsService(t, func(w http.ResponseWriter, r *http.Request) {
+		var body batchUpdateBody
+		b, _ := io.ReadAll(r.Body)
+		json.Unmarshal(b, &body)
+
+		var rs []string
+		for _, d := range body.Data {
+			rs = append(rs, d.Range)
+		}
+		ranges = append(ranges, rs)
+
+		writeJSON(t, w, map[string]interface{}{})
+	})
+
+	err := srv.BatchUpdate(context.Background(), "sheet-id", []RangeValues{
+		{Range: "Sheet1!B2", Values: [][]interface{}{{1, 2}, {3, 4}, {5, 6}}},
+		{Range: "Other!A1", Values: [][]interface{}{{"x"}}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := [][]string{{"Sheet1!B2"}, {"Sheet1!B4", "Other!A1"}}
+	if !reflect.DeepEqual(ranges, expected) {
+		t.Errorf("expected %v, got %v", expected, ranges)
+	}
+}
+
+func TestSheetsService_UpsertRows(t *testing.T) {
+	var written batchUpdateBody
+	srv := newTestSheetsService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			writeJSON(t, w, map[string]interface{}{
+				"range": "Pharmacies!A1:C1000",
+				"values": [][]interface{}{
+					{"NPI", "Name", "Notes"},
+					{"111", "Old Name", "keep me"},
+					{"222", "Other", ""},
+				},
+			})
+			return
+		}
+
+		b, _ := io.ReadAll(r.Body)
+		json.Unmarshal(b, &written)
+		writeJSON(t, w, map[string]interface{}{})
+	})
+
+	result, err := srv.UpsertRows(context.Background(), "sheet-id", "Pharmacies!A1:C", "npi", [][]interface{}{
+		{"Name", "NPI"},
+		{"New Name", "111"},
+		{"Added", "333"},
+		{"Added Twice", "333"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if *result != (UpsertResult{Updated: 1, Appended: 1}) {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	if len(written.Data) != 2 {
+		t.Fatalf("expected 2 ranges, got %+v", written.Data)
+	}
+	// the Notes column is not in rows and is not written
+	if written.Data[0].Range != "Pharmacies!A2" || !reflect.DeepEqual(written.Data[0].Values, [][]interface{}{{"111", "New Name"}}) {
+		t.Errorf("unexpected update %+v", written.Data[0])
+	}
+	if written.Data[1].Range != "Pharmacies!A4" || !reflect.DeepEqual(written.Data[1].Values, [][]interface{}{{"333", "Added Twice"}}) {
+		t.Errorf("unexpected append %+v", written.Data[1])
+	}
+}
+
+func TestSheetsService_UpsertRows_WritesOnlyInputColumns(t *testing.T) {
+	var written batchUpdateBody
+	srv := newTestSheetsService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			writeJSON(t, w, map[string]interface{}{
+				"range":  "Pharmacies!B1:E1000",
+				"values": [][]interface{}{{"NPI", "Total", "Fills", "Status"}, {"111", "1,234.50", "3", "new"}},
+			})
+			return
+		}
+
+		b, _ := io.ReadAll(r.Body)
+		json.Unmarshal(b, &written)
+		writeJSON(t, w, map[string]interface{}{})
+	})
+
+	// Total may be a formula and Fills a formatted number, they must not be written back
+	_, err := srv.UpsertRows(context.Background(), "sheet-id", "Pharmacies!B:E", "NPI", [][]interface{}{
+		{"Status", "NPI"},
+		{"active", "111"},
+		{"new", "222"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var ranges []string
+	for _, d := range written.Data {
+		ranges = append(ranges, d.Range)
+	}
+	if expected := []string{"Pharmacies!B2", "Pharmacies!E2", "Pharmacies!B3", "Pharmacies!E3"}; !reflect.DeepEqual(ranges, expected) {
+		t.Fatalf("expected ranges %v, got %v", expected, ranges)
+	}
+	if !reflect.DeepEqual(written.Data[1].Values, [][]interface{}{{"active"}}) {
+		t.Errorf("unexpected status update %+v", written.Data[1])
+	}
+}
+
+func TestSheetsService_UpsertRows_UnknownColumn(t *testing.T) {
+	srv := newTestSheetsService(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, map[string]interface{}{"values": [][]interface{}{{"NPI"}}})
+	})
+
+	_, err := srv.UpsertRows(context.Background(), "sheet-id", "Sheet1", "NPI", [][]interface{}{{"NPI", "Missing"}})
+	if err == nil || !strings.Contains(err.Error(), "Missing") {
+		t.Errorf("expected unknown column error, got %v", err)
+	}
+}
+
+func TestCellRange(t *testing.T) {
+	tests := map[string]string{
+		"Sheet1!B2:C":            "Sheet1!D7",
+		"'Q1 Data'!A1":           "'Q1 Data'!D7",
+		"Jan!A1:Z1000":           "Jan!D7",
+		"'Bob''s Pharmacies'!A1": "'Bob''s Pharmacies'!D7",
+	}
+
+	for a1Range, expected := range tests {
+		if got := cellRange(a1Range, 3, 7); got != expected {
+			t.Errorf("cellRange(%q) = %s, want %s", a1Range, got, expected)
+		}
+	}
+}
+
+func TestSheetsService_UpsertRows_ThreeLetterTab(t *testing.T) {
+	var written batchUpdateBody
+	srv := newTestSheetsService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			// the API qualifies the range, "Jan" could otherwise be the cell JAN1 of the first tab
+			writeJSON(t, w, map[string]interface{}{
+				"range":  "Jan!A1:Z1000",
+				"values": [][]interface{}{{"NPI", "Name"}, {"111", "Old Name"}},
+			})
+			return
+		}
+
+		b, _ := io.ReadAll(r.Body)
+		json.Unmarshal(b, &written)
+		writeJSON(t, w, map[string]interface{}{})
+	})
+
+	if _, err := srv.UpsertRows(context.Background(), "sheet-id", "Jan", "NPI", [][]interface{}{{"NPI", "Name"}, {"111", "New Name"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(written.Data) != 1 || !strings.HasPrefix(written.Data[0].Range, "Jan!") {
+		t.Errorf("expected the write to target the Jan tab, got %+v", written.Data)
+	}
+}
+
+func TestSheetsService_BatchUpdate_RequiresSheetToSplit(t *testing.T) {
+	defer func(n int) { MaxCellsPerRequest = n }(MaxCellsPerRequest)
+	MaxCellsPerRequest = 2
+
+	srv := newTestSheetsService(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, map[string]interface{}{})
+	})
+
+	err := srv.BatchUpdate(context.Background(), "sheet-id", []RangeValues{{Range: "Jan", Values: [][]interface{}{{1, 2}, {3, 4}}}})
+	if !errors.Is(err, ErrInvalidRange) {
+		t.Errorf("expected ErrInvalidRange for a bare range split in chunks, got %v", err)
+	}
+}
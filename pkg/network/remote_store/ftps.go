@@ -0,0 +1,199 @@
+package remote_store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/jlaffaye/ftp"
+	"github.com/phil-inc/pcommon/pkg/network"
+)
+
+// FTPSStore is a RemoteStore backed by an FTPS server
+type FTPSStore struct {
+	client *network.FTPSClient
+	root   string
+}
+
+// NewFTPSStore returns a store rooted at root on an already connected client
+func NewFTPSStore(client *network.FTPSClient, root string) *FTPSStore {
+	return &FTPSStore{client: client, root: root}
+}
+
+// openFTPS connects using the TLS settings of opts.FTPS and the address and
+// credentials of the URL. The implicit=true and disable_epsv=true query
+// parameters map to the corresponding FTPSConfig fields.
+func openFTPS(ctx context.Context, u *url.URL, root string, opts Options) (*FTPSStore, error) {
+	config := opts.FTPS
+	config.Host = u.Hostname()
+	config.Port = u.Port()
+	config.Username = u.User.Username()
+	config.Password = password(u, opts)
+
+	q := u.Query()
+	if q.Get("implicit") == "true" {
+		config.ImplicitTLS = true
+	}
+	if q.Get("disable_epsv") == "true" {
+		config.DisableEPSV = true
+	}
+
+	if config.Port == "" {
+		config.Port = "21"
+		if config.ImplicitTLS {
+			config.Port = "990"
+		}
+	}
+
+	client, err := network.NewFTPSClient(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFTPSStore(client, root), nil
+}
+
+// Put creates the missing parent directories and uploads to a temporary file renamed into place
+// once complete, so that a partially written file is never visible on the server
+func (s *FTPSStore) Put(ctx context.Context, name string, r io.Reader) error {
+	p, err := resolve(s.root, name)
+	if err != nil {
+		return err
+	}
+
+	if err := s.mkdirAll(ctx, path.Dir(p)); err != nil {
+		return err
+	}
+
+	tmp := path.Join(path.Dir(p), fmt.Sprintf(".%s.%d.part", path.Base(p), time.Now().UnixNano()))
+	if err := s.client.Upload(ctx, r, tmp); err != nil {
+		s.client.Delete(ctx, tmp)
+		return err
+	}
+
+	if err := s.client.Rename(ctx, tmp, p); err != nil {
+		// some servers refuse to rename over an existing file
+		s.client.Delete(ctx, p)
+		if err := s.client.Rename(ctx, tmp, p); err != nil {
+			s.client.Delete(ctx, tmp)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mkdirAll creates dir and its parents, FTP has no way to tell an existing directory from a
+// failure so errors are ignored and reported by the upload
+func (s *FTPSStore) mkdirAll(ctx context.Context, dir string) error {
+	current := ""
+	if strings.HasPrefix(dir, "/") {
+		current = "/"
+	}
+
+	for _, part := range strings.Split(dir, "/") {
+		if part == "" || part == "." {
+			continue
+		}
+		current = path.Join(current, part)
+
+		if err := s.client.MakeDir(ctx, current); err != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+func (s *FTPSStore) Get(ctx context.Context, name string, w io.Writer) error {
+	p, err := resolve(s.root, name)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Download(ctx, p, w)
+	return ftpNotExist(p, err)
+}
+
+func (s *FTPSStore) List(ctx context.Context, dir string) ([]FileInfo, error) {
+	p, err := resolve(s.root, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.client.List(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]FileInfo, 0, len(entries))
+	for _, e := range entries {
+		if e.Name == "." || e.Name == ".." {
+			continue
+		}
+		files = append(files, s.fileInfo(path.Join(p, e.Name), e))
+	}
+
+	return files, nil
+}
+
+func (s *FTPSStore) Delete(ctx context.Context, name string) error {
+	p, err := resolve(s.root, name)
+	if err != nil {
+		return err
+	}
+
+	return ftpNotExist(p, s.client.Delete(ctx, p))
+}
+
+// Stat looks the file up in the listing of its parent, as SIZE and MDTM are not supported by every server
+func (s *FTPSStore) Stat(ctx context.Context, name string) (*FileInfo, error) {
+	p, err := resolve(s.root, name)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.client.List(ctx, path.Dir(p))
+	if err != nil {
+		return nil, ftpNotExist(p, err)
+	}
+
+	for _, e := range entries {
+		if e.Name == path.Base(p) {
+			fi := s.fileInfo(p, e)
+			return &fi, nil
+		}
+	}
+
+	return nil, fmt.Errorf("stat %s: %w", p, os.ErrNotExist)
+}
+
+func (s *FTPSStore) Close() error {
+	return s.client.Close()
+}
+
+func (s *FTPSStore) fileInfo(p string, e *ftp.Entry) FileInfo {
+	return FileInfo{
+		Name:    relative(s.root, p),
+		Size:    int64(e.Size),
+		ModTime: e.Time,
+		IsDir:   e.Type == ftp.EntryTypeFolder,
+	}
+}
+
+// ftpNotExist maps the 550 file unavailable reply to os.ErrNotExist
+func ftpNotExist(p string, err error) error {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) && protoErr.Code == ftp.StatusFileUnavailable {
+		return fmt.Errorf("%s: %w", p, os.ErrNotExist)
+	}
+
+	return err
+}
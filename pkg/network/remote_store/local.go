@@ -0,0 +1,137 @@
+package remote_store
+
+import (
+	"context"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// LocalStore is a RemoteStore backed by a directory on the local filesystem,
+// mostly useful for local development and tests
+type LocalStore struct {
+	root string
+}
+
+// NewLocalStore returns a store rooted at the local directory root
+func NewLocalStore(root string) *LocalStore {
+	return &LocalStore{root: filepath.ToSlash(root)}
+}
+
+func (s *LocalStore) resolve(name string) (string, error) {
+	p, err := resolve(s.root, name)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.FromSlash(p), nil
+}
+
+// Put writes to a temporary file and renames it, so readers never see a partial file
+func (s *LocalStore) Put(ctx context.Context, name string, r io.Reader) error {
+	p, err := s.resolve(name)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(p), "."+filepath.Base(p)+".*.part")
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return os.Rename(f.Name(), p)
+}
+
+func (s *LocalStore) Get(ctx context.Context, name string, w io.Writer) error {
+	p, err := s.resolve(name)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
+
+func (s *LocalStore) List(ctx context.Context, dir string) ([]FileInfo, error) {
+	p, err := s.resolve(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(p)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]FileInfo, 0, len(entries))
+	for _, e := range entries {
+		fi, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, FileInfo{
+			Name:    relative(s.root, path.Join(filepath.ToSlash(p), e.Name())),
+			Size:    fi.Size(),
+			ModTime: fi.ModTime(),
+			IsDir:   fi.IsDir(),
+		})
+	}
+
+	return files, nil
+}
+
+func (s *LocalStore) Delete(ctx context.Context, name string) error {
+	p, err := s.resolve(name)
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(p)
+}
+
+func (s *LocalStore) Stat(ctx context.Context, name string) (*FileInfo, error) {
+	p, err := s.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileInfo{
+		Name:    relative(s.root, filepath.ToSlash(p)),
+		Size:    fi.Size(),
+		ModTime: fi.ModTime(),
+		IsDir:   fi.IsDir(),
+	}, nil
+}
+
+func (s *LocalStore) Close() error {
+	return nil
+}
@@ -0,0 +1,128 @@
+package remote_store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/phil-inc/pcommon/pkg/network"
+	"github.com/phil-inc/pcommon/pkg/s3"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	SCHEME_SFTP = "sftp"
+	SCHEME_FTPS = "ftps"
+	SCHEME_S3   = "s3"
+	SCHEME_FILE = "file"
+)
+
+// ErrPathOutsideRoot is returned when a name resolves outside of the store root, e.g. "../secrets"
+var ErrPathOutsideRoot = errors.New("path is outside of the store root")
+
+// FileInfo describes a file held by a RemoteStore
+type FileInfo struct {
+	// Name is the path of the file relative to the store root
+	Name    string
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+}
+
+// RemoteStore is a protocol independent destination for files. Names are
+// slash separated paths relative to the root given in the store URL.
+// Stat and Get return an error matching os.ErrNotExist for missing files.
+// Cancelling ctx aborts a running sftp or ftps operation by closing the
+// connection, so the store must be opened again afterwards.
+type RemoteStore interface {
+	// Put writes the content of r to name, replacing any existing file
+	Put(ctx context.Context, name string, r io.Reader) error
+	// Get copies the content of name into w
+	Get(ctx context.Context, name string, w io.Writer) error
+	// List returns the entries directly under dir, use "" for the root
+	List(ctx context.Context, dir string) ([]FileInfo, error)
+	// Delete removes name
+	Delete(ctx context.Context, name string) error
+	// Stat returns the details of name
+	Stat(ctx context.Context, name string) (*FileInfo, error)
+	// Close releases the underlying connection
+	Close() error
+}
+
+// Options holds the settings that cannot be expressed in the store URL
+type Options struct {
+	// Password is used for sftp and ftps when the URL has none
+	Password string
+	// PrivateKey is a PEM encoded key used for sftp instead of a password
+	PrivateKey string
+	// HostKeyCallback verifies the sftp server key, defaults to the callback set with network.SetDefaultHostKeyCallback
+	HostKeyCallback ssh.HostKeyCallback
+	// FTPS holds the TLS settings for ftps, connection details are taken from the URL
+	FTPS network.FTPSConfig
+	// S3Client is required for s3 URLs
+	S3Client *s3.S3Client
+}
+
+// Open returns the store described by rawURL, for example
+//
+//	sftp://user@host:22/outbound
+//	ftps://user@host:990/inbound?implicit=true
+//	s3://bucket/reports
+//	file:///var/data/reports
+func Open(ctx context.Context, rawURL string, opts Options) (RemoteStore, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	root := u.Path
+	if root == "" {
+		root = "/"
+	}
+
+	switch u.Scheme {
+	case SCHEME_SFTP:
+		return openSFTP(u, root, opts)
+	case SCHEME_FTPS:
+		return openFTPS(ctx, u, root, opts)
+	case SCHEME_S3:
+		if opts.S3Client == nil {
+			return nil, errors.New("an S3 client is required for s3 URLs")
+		}
+		return NewS3Store(opts.S3Client, u.Host, strings.TrimPrefix(u.Path, "/")), nil
+	case SCHEME_FILE:
+		return NewLocalStore(root), nil
+	default:
+		return nil, fmt.Errorf("unsupported remote store scheme %q", u.Scheme)
+	}
+}
+
+// password returns the URL password or the one from the options
+func password(u *url.URL, opts Options) string {
+	if p, ok := u.User.Password(); ok {
+		return p
+	}
+	return opts.Password
+}
+
+// resolve joins name to root and makes sure the result stays within root
+func resolve(root, name string) (string, error) {
+	root = path.Clean(root)
+	full := path.Join(root, name)
+
+	if full != root && !strings.HasPrefix(full, strings.TrimSuffix(root, "/")+"/") {
+		return "", fmt.Errorf("%w: %s", ErrPathOutsideRoot, name)
+	}
+
+	return full, nil
+}
+
+// relative returns the store relative name of the full path p
+func relative(root, p string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(p, strings.TrimSuffix(path.Clean(root), "/")), "/")
+}
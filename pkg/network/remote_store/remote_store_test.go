@@ -0,0 +1,220 @@
+package remote_store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/phil-inc/pcommon/pkg/internal/ftptest"
+	"github.com/phil-inc/pcommon/pkg/internal/sftptest"
+	"github.com/phil-inc/pcommon/pkg/network"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		root     string
+		name     string
+		expected string
+		err      bool
+	}{
+		{root: "/outbound", name: "report.csv", expected: "/outbound/report.csv"},
+		{root: "/outbound/", name: "2024/report.csv", expected: "/outbound/2024/report.csv"},
+		{root: "/outbound", name: "", expected: "/outbound"},
+		{root: "/", name: "report.csv", expected: "/report.csv"},
+		{root: "/outbound", name: "/report.csv", expected: "/outbound/report.csv"},
+		{root: "/outbound", name: "../secrets", err: true},
+		{root: "/outbound", name: "../outbound2/report.csv", err: true},
+	}
+
+	for _, test := range tests {
+		got, err := resolve(test.root, test.name)
+		if test.err {
+			if !errors.Is(err, ErrPathOutsideRoot) {
+				t.Errorf("resolve(%q, %q): expected ErrPathOutsideRoot, got %v", test.root, test.name, err)
+			}
+			continue
+		}
+
+		if err != nil || got != test.expected {
+			t.Errorf("resolve(%q, %q) = %q, %v; want %q", test.root, test.name, got, err, test.expected)
+		}
+	}
+}
+
+func TestOpen_InvalidURL(t *testing.T) {
+	ctx := context.Background()
+
+	if _, err := Open(ctx, "gopher://host/path", Options{}); err == nil {
+		t.Errorf("expected error for unsupported scheme")
+	}
+
+	if _, err := Open(ctx, "s3://bucket/reports", Options{}); err == nil {
+		t.Errorf("expected error when S3 client is missing")
+	}
+}
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+
+	store, err := Open(ctx, "file://"+filepath.ToSlash(root), Options{})
+	if err != nil {
+		t.Fatalf("unexpected open error: %v", err)
+	}
+	defer store.Close()
+
+	testStore(t, store)
+}
+
+func TestSFTPStore(t *testing.T) {
+	store := newTestSFTPStore(t)
+
+	testStore(t, store)
+}
+
+func TestSFTPStore_Cancel(t *testing.T) {
+	store := newTestSFTPStore(t)
+
+	// the upload never ends unless the cancellation closes the connection
+	ctx, cancel := context.WithCancel(context.Background())
+	err := store.Put(ctx, "endless.csv", &cancelReader{cancel: cancel})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := store.Stat(ctx, "endless.csv"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled for a cancelled context, got %v", err)
+	}
+}
+
+func TestFTPSStore(t *testing.T) {
+	ctx := context.Background()
+	server := ftptest.NewServer(t)
+
+	store, err := Open(ctx, fmt.Sprintf("ftps://phil@%s/outbound", net.JoinHostPort(server.Host, server.Port)), Options{
+		Password: "secret",
+		FTPS:     network.FTPSConfig{RootCAsPEM: server.CertPEM},
+	})
+	if err != nil {
+		t.Fatalf("unexpected open error: %v", err)
+	}
+	defer store.Close()
+
+	testStore(t, store)
+
+	// Put creates the missing directories and leaves no temporary file behind
+	if err := store.Put(ctx, "2025/01/report.csv", strings.NewReader("a,b\n")); err != nil {
+		t.Fatalf("unexpected put error: %v", err)
+	}
+	if err := store.Put(ctx, "2025/01/report.csv", strings.NewReader("c,d\n")); err != nil {
+		t.Fatalf("unexpected error replacing a file: %v", err)
+	}
+	if !server.IsDir("/outbound/2025/01") {
+		t.Error("expected the parent directories to be created")
+	}
+	if files := server.Files(); !reflect.DeepEqual(files, []string{"/outbound/2025/01/report.csv"}) {
+		t.Errorf("expected only the renamed file, got %v", files)
+	}
+	if data, _ := server.File("/outbound/2025/01/report.csv"); string(data) != "c,d\n" {
+		t.Errorf("expected the file to be replaced, got %q", data)
+	}
+}
+
+func newTestSFTPStore(t *testing.T) RemoteStore {
+	t.Helper()
+
+	address, hostKey := sftptest.NewServer(t)
+	root := filepath.ToSlash(t.TempDir())
+
+	store, err := Open(context.Background(), fmt.Sprintf("sftp://%s@%s%s", sftptest.User, address, root), Options{
+		Password:        sftptest.Password,
+		HostKeyCallback: ssh.FixedHostKey(hostKey),
+	})
+	if err != nil {
+		t.Fatalf("unexpected open error: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	return store
+}
+
+// cancelReader returns data forever, cancelling its context once the first chunk is read
+type cancelReader struct {
+	cancel context.CancelFunc
+}
+
+func (r *cancelReader) Read(p []byte) (int, error) {
+	r.cancel()
+	return len(p), nil
+}
+
+// testStore checks the behaviour shared by every RemoteStore
+func testStore(t *testing.T, store RemoteStore) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "2024/report.csv", strings.NewReader("a,b\n")); err != nil {
+		t.Fatalf("unexpected put error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := store.Get(ctx, "2024/report.csv", &buf); err != nil {
+		t.Fatalf("unexpected get error: %v", err)
+	}
+	if buf.String() != "a,b\n" {
+		t.Errorf("unexpected content %q", buf.String())
+	}
+
+	files, err := store.List(ctx, "2024")
+	if err != nil {
+		t.Fatalf("unexpected list error: %v", err)
+	}
+	if len(files) != 1 || files[0].Name != "2024/report.csv" || files[0].Size != 4 {
+		t.Errorf("unexpected listing %+v", files)
+	}
+
+	fi, err := store.Stat(ctx, "2024/report.csv")
+	if err != nil {
+		t.Fatalf("unexpected stat error: %v", err)
+	}
+	if fi.Name != "2024/report.csv" || fi.IsDir {
+		t.Errorf("unexpected stat %+v", fi)
+	}
+
+	if err := store.Delete(ctx, "2024/report.csv"); err != nil {
+		t.Fatalf("unexpected delete error: %v", err)
+	}
+	if _, err := store.Stat(ctx, "2024/report.csv"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected os.ErrNotExist, got %v", err)
+	}
+	if err := store.Get(ctx, "2024/report.csv", &buf); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected os.ErrNotExist from get, got %v", err)
+	}
+
+	if err := store.Put(ctx, "../escape.csv", strings.NewReader("x")); !errors.Is(err, ErrPathOutsideRoot) {
+		t.Errorf("expected ErrPathOutsideRoot, got %v", err)
+	}
+}
+
+func TestFTPNotExist(t *testing.T) {
+	err := ftpNotExist("/outbound/report.csv", &textproto.Error{Code: 550, Msg: "No such file or directory"})
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected 550 to match os.ErrNotExist, got %v", err)
+	}
+
+	if err := ftpNotExist("/outbound/report.csv", &textproto.Error{Code: 530, Msg: "Not logged in"}); errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected other replies to be returned as is, got %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package remote_store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/phil-inc/pcommon/pkg/s3"
+)
+
+// S3Store is a RemoteStore backed by an S3 bucket, keys are prefixed with the URL path
+type S3Store struct {
+	client *s3.S3Client
+	bucket string
+	prefix string
+}
+
+// NewS3Store returns a store for the keys under prefix in bucket
+func NewS3Store(client *s3.S3Client, bucket, prefix string) *S3Store {
+	return &S3Store{client: client, bucket: bucket, prefix: strings.Trim(prefix, "/")}
+}
+
+func (s *S3Store) key(name string) (string, error) {
+	p, err := resolve("/"+s.prefix, name)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimPrefix(p, "/"), nil
+}
+
+func (s *S3Store) Put(ctx context.Context, name string, r io.Reader) error {
+	key, err := s.key(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.UploadFile(ctx, s.bucket, key, r)
+	return err
+}
+
+func (s *S3Store) Get(ctx context.Context, name string, w io.Writer) error {
+	key, err := s.key(name)
+	if err != nil {
+		return err
+	}
+
+	out, err := s.client.Client.GetObject(ctx, &awss3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return notExist(key, err)
+	}
+
+	defer out.Body.Close()
+
+	_, err = io.Copy(w, out.Body)
+	return err
+}
+
+// List returns the objects and common prefixes directly under dir
+func (s *S3Store) List(ctx context.Context, dir string) ([]FileInfo, error) {
+	prefix, err := s.key(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	paginator := awss3.NewListObjectsV2Paginator(s.client.Client, &awss3.ListObjectsV2Input{
+		Bucket:    aws.String(s.bucket),
+		Prefix:    aws.String(prefix),
+		Delimiter: aws.String("/"),
+	})
+
+	files := []FileInfo{}
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range page.CommonPrefixes {
+			files = append(files, FileInfo{
+				Name:  relative("/"+s.prefix, "/"+strings.TrimSuffix(aws.ToString(p.Prefix), "/")),
+				IsDir: true,
+			})
+		}
+
+		for _, o := range page.Contents {
+			files = append(files, FileInfo{
+				Name:    relative("/"+s.prefix, "/"+aws.ToString(o.Key)),
+				Size:    aws.ToInt64(o.Size),
+				ModTime: aws.ToTime(o.LastModified),
+			})
+		}
+	}
+
+	return files, nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, name string) error {
+	key, err := s.key(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Client.DeleteObject(ctx, &awss3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+func (s *S3Store) Stat(ctx context.Context, name string) (*FileInfo, error) {
+	key, err := s.key(name)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := s.client.Client.HeadObject(ctx, &awss3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, notExist(key, err)
+	}
+
+	return &FileInfo{
+		Name:    relative("/"+s.prefix, "/"+key),
+		Size:    aws.ToInt64(out.ContentLength),
+		ModTime: aws.ToTime(out.LastModified),
+	}, nil
+}
+
+func (s *S3Store) Close() error {
+	return nil
+}
+
+// notExist maps the S3 not found errors to os.ErrNotExist
+func notExist(key string, err error) error {
+	var nf *types.NotFound
+	var nsk *types.NoSuchKey
+	if errors.As(err, &nf) || errors.As(err, &nsk) {
+		return fmt.Errorf("%s: %w", key, os.ErrNotExist)
+	}
+
+	return err
+}
@@ -0,0 +1,182 @@
+package remote_store
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/url"
+	"path"
+
+	"github.com/phil-inc/pcommon/pkg/network"
+	"golang.org/x/crypto/ssh"
+)
+
+// SFTPStore is a RemoteStore backed by an SFTP server
+type SFTPStore struct {
+	client *network.SFTPClient
+	root   string
+}
+
+// NewSFTPStore returns a store rooted at root on an already connected client
+func NewSFTPStore(client *network.SFTPClient, root string) *SFTPStore {
+	return &SFTPStore{client: client, root: root}
+}
+
+func openSFTP(u *url.URL, root string, opts Options) (*SFTPStore, error) {
+	address := u.Host
+	if u.Port() == "" {
+		address = net.JoinHostPort(u.Hostname(), "22")
+	}
+
+	user := u.User.Username()
+
+	var client *network.SFTPClient
+	var err error
+
+	switch {
+	case opts.HostKeyCallback != nil:
+		config := &ssh.ClientConfig{
+			User:            user,
+			HostKeyCallback: opts.HostKeyCallback,
+			Auth: []ssh.AuthMethod{
+				ssh.Password(password(u, opts)),
+			},
+		}
+
+		if opts.PrivateKey != "" {
+			signer, err := ssh.ParsePrivateKey([]byte(opts.PrivateKey))
+			if err != nil {
+				return nil, err
+			}
+			config.Auth = []ssh.AuthMethod{ssh.PublicKeys(signer)}
+		}
+
+		client, err = network.NewSFTPClient(config, address)
+	case opts.PrivateKey != "":
+		client, err = network.NewSFTPClientWithPrivateKey(user, opts.PrivateKey, address)
+	default:
+		client, err = network.NewSFTPClientWithPassword(user, password(u, opts), address)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSFTPStore(client, root), nil
+}
+
+// Put uploads to a temporary name and renames it, so partners never pick up a partial file
+func (s *SFTPStore) Put(ctx context.Context, name string, r io.Reader) error {
+	p, err := resolve(s.root, name)
+	if err != nil {
+		return err
+	}
+
+	stop := s.watch(ctx)
+
+	if err := s.client.MkdirAll(path.Dir(p)); err != nil {
+		return stop(err)
+	}
+
+	_, err = s.client.UploadAtomic(r, p)
+	return stop(err)
+}
+
+func (s *SFTPStore) Get(ctx context.Context, name string, w io.Writer) error {
+	p, err := resolve(s.root, name)
+	if err != nil {
+		return err
+	}
+
+	stop := s.watch(ctx)
+	_, err = s.client.Download(p, w)
+	return stop(err)
+}
+
+func (s *SFTPStore) List(ctx context.Context, dir string) ([]FileInfo, error) {
+	p, err := resolve(s.root, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	stop := s.watch(ctx)
+	entries, err := s.client.List(p, "")
+	if err = stop(err); err != nil {
+		return nil, err
+	}
+
+	files := make([]FileInfo, 0, len(entries))
+	for _, e := range entries {
+		files = append(files, FileInfo{
+			Name:    relative(s.root, path.Join(p, e.Name())),
+			Size:    e.Size(),
+			ModTime: e.ModTime(),
+			IsDir:   e.IsDir(),
+		})
+	}
+
+	return files, nil
+}
+
+func (s *SFTPStore) Delete(ctx context.Context, name string) error {
+	p, err := resolve(s.root, name)
+	if err != nil {
+		return err
+	}
+
+	stop := s.watch(ctx)
+	return stop(s.client.Remove(p))
+}
+
+func (s *SFTPStore) Stat(ctx context.Context, name string) (*FileInfo, error) {
+	p, err := resolve(s.root, name)
+	if err != nil {
+		return nil, err
+	}
+
+	stop := s.watch(ctx)
+	fi, err := s.client.Stat(p)
+	if err = stop(err); err != nil {
+		return nil, err
+	}
+
+	return &FileInfo{
+		Name:    relative(s.root, p),
+		Size:    fi.Size(),
+		ModTime: fi.ModTime(),
+		IsDir:   fi.IsDir(),
+	}, nil
+}
+
+func (s *SFTPStore) Close() error {
+	return s.client.Close()
+}
+
+// watch closes the client if ctx is cancelled before the returned stop function is called, which
+// aborts the running operation as SFTP has no way to cancel a request. stop waits for the watcher
+// to exit and returns the context error in place of err when the operation was aborted.
+func (s *SFTPStore) watch(ctx context.Context) func(err error) error {
+	if ctx.Done() == nil {
+		return func(err error) error { return err }
+	}
+
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-ctx.Done():
+			s.client.Close()
+		case <-done:
+		}
+	}()
+
+	return func(err error) error {
+		close(done)
+		<-exited
+		if err != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+}
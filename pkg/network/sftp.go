@@ -3,7 +3,6 @@ package network
 import (
 	"fmt"
 	"io"
-	"net"
 	"os"
 
 	"github.com/pkg/sftp"
@@ -17,9 +16,7 @@ func UploadFileToSFTP(host, user, password, port, srcFile, dstFile string) (int6
 		Auth: []ssh.AuthMethod{
 			ssh.Password(password),
 		},
-		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-			return nil
-		},
+		HostKeyCallback: hostKeyCallback(),
 	}
 
 	address := fmt.Sprintf("%s:%s", host, port)
@@ -34,9 +31,7 @@ func UploadFileToSFTPWithAddress(user, password, address, srcFile, dstFile strin
 		Auth: []ssh.AuthMethod{
 			ssh.Password(password),
 		},
-		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-			return nil
-		},
+		HostKeyCallback: hostKeyCallback(),
 	}
 
 	return UploadFileToSFTPUsingConfig(config, address, srcFile, dstFile)
@@ -89,10 +84,8 @@ func UploadFileToSFTPUsingConfig(config *ssh.ClientConfig, address, srcFile, dst
 func UploadFileToSFTPUsingPrivateKey(user, privateKey, address, srcFile, dstFile string) (int64, error) {
 	signer, _ := ssh.ParsePrivateKey([]byte(privateKey))
 	config := &ssh.ClientConfig{
-		User: user,
-		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-			return nil
-		},
+		User:            user,
+		HostKeyCallback: hostKeyCallback(),
 		Auth: []ssh.AuthMethod{
 			ssh.PublicKeys(signer),
 		},
@@ -112,9 +105,7 @@ func ReadFileFromSFTP(host, user, password, port, srcFile, dstFile string) (int6
 		Auth: []ssh.AuthMethod{
 			ssh.Password(password),
 		},
-		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-			return nil
-		},
+		HostKeyCallback: hostKeyCallback(),
 	}
 
 	address := fmt.Sprintf("%s:%s", host, port)
@@ -129,9 +120,7 @@ func ReadFileFromSFTPWithAddress(user, password, address, srcFile, dstFile strin
 		Auth: []ssh.AuthMethod{
 			ssh.Password(password),
 		},
-		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-			return nil
-		},
+		HostKeyCallback: hostKeyCallback(),
 	}
 
 	return ReadFileFromSFTPUsingConfig(config, address, srcFile, dstFile)
@@ -182,10 +171,8 @@ func ReadFileFromSFTPUsingConfig(config *ssh.ClientConfig, address, srcFile, dst
 func ReadFileFromSFTPUsingPrivateKey(user, privateKey, address, srcFile, dstFile string) (int64, error) {
 	signer, _ := ssh.ParsePrivateKey([]byte(privateKey))
 	config := &ssh.ClientConfig{
-		User: user,
-		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-			return nil
-		},
+		User:            user,
+		HostKeyCallback: hostKeyCallback(),
 		Auth: []ssh.AuthMethod{
 			ssh.PublicKeys(signer),
 		},
@@ -196,10 +183,8 @@ func ReadFileFromSFTPUsingPrivateKey(user, privateKey, address, srcFile, dstFile
 
 func ReadFileFromLegacySFTP(usr, password, address, srcFile, dstFile string) (int64, error) {
 	config := &ssh.ClientConfig{
-		User: usr,
-		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-			return nil
-		},
+		User:            usr,
+		HostKeyCallback: hostKeyCallback(),
 		Auth: []ssh.AuthMethod{
 			ssh.Password(password),
 		},
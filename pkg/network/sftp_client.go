@@ -0,0 +1,195 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// posixRenameExtension is advertised by OpenSSH servers that can rename over an existing file
+	posixRenameExtension = "posix-rename@openssh.com"
+
+	// atomicUploadSuffix is appended to the temporary file name used by UploadAtomic
+	atomicUploadSuffix = ".part"
+)
+
+// SFTPClient keeps a single SSH connection and SFTP session open so that
+// several remote operations can be performed without reconnecting.
+// The caller is responsible for calling Close once done.
+type SFTPClient struct {
+	conn   *ssh.Client
+	client *sftp.Client
+}
+
+// NewSFTPClient connects to the SFTP server at address using the given ssh config
+func NewSFTPClient(config *ssh.ClientConfig, address string) (*SFTPClient, error) {
+	conn, err := ssh.Dial("tcp", address, config)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &SFTPClient{conn: conn, client: client}, nil
+}
+
+// NewSFTPClientWithPassword connects to the SFTP server at address using password authentication
+func NewSFTPClientWithPassword(user, password, address string) (*SFTPClient, error) {
+	config := &ssh.ClientConfig{
+		User: user,
+		Auth: []ssh.AuthMethod{
+			ssh.Password(password),
+		},
+		HostKeyCallback: hostKeyCallback(),
+	}
+
+	return NewSFTPClient(config, address)
+}
+
+// NewSFTPClientWithPrivateKey connects to the SFTP server at address using a PEM encoded private key
+func NewSFTPClientWithPrivateKey(user, privateKey, address string) (*SFTPClient, error) {
+	signer, err := ssh.ParsePrivateKey([]byte(privateKey))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse private key: %w", err)
+	}
+
+	config := &ssh.ClientConfig{
+		User:            user,
+		HostKeyCallback: hostKeyCallback(),
+		Auth: []ssh.AuthMethod{
+			ssh.PublicKeys(signer),
+		},
+	}
+
+	return NewSFTPClient(config, address)
+}
+
+// Close ends the SFTP session and the underlying SSH connection
+func (c *SFTPClient) Close() error {
+	err := c.client.Close()
+	if cerr := c.conn.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// Upload copies everything from r into dstFile, truncating dstFile if it already exists
+func (c *SFTPClient) Upload(r io.Reader, dstFile string) (int64, error) {
+	df, err := c.client.Create(dstFile)
+	if err != nil {
+		return 0, err
+	}
+
+	bytes, err := io.Copy(df, r)
+	if err != nil {
+		df.Close()
+		return 0, err
+	}
+
+	return bytes, df.Close()
+}
+
+// UploadAtomic uploads r to a temporary file next to dstFile and renames it into
+// place once the transfer has completed, so that a partially written dstFile is
+// never visible to the server side.
+func (c *SFTPClient) UploadAtomic(r io.Reader, dstFile string) (int64, error) {
+	tmpFile := path.Join(path.Dir(dstFile), fmt.Sprintf(".%s.%d%s", path.Base(dstFile), time.Now().UnixNano(), atomicUploadSuffix))
+
+	bytes, err := c.Upload(r, tmpFile)
+	if err != nil {
+		c.client.Remove(tmpFile)
+		return 0, err
+	}
+
+	if err := c.Rename(tmpFile, dstFile); err != nil {
+		c.client.Remove(tmpFile)
+		return 0, err
+	}
+
+	return bytes, nil
+}
+
+// Download copies the content of srcFile into w
+func (c *SFTPClient) Download(srcFile string, w io.Writer) (int64, error) {
+	sf, err := c.client.Open(srcFile)
+	if err != nil {
+		return 0, err
+	}
+
+	defer sf.Close()
+
+	return io.Copy(w, sf)
+}
+
+// List returns the entries of dir sorted by name. When pattern is not empty only
+// entries whose name matches the pattern are returned, using path.Match syntax (e.g. "*.csv").
+func (c *SFTPClient) List(dir, pattern string) ([]os.FileInfo, error) {
+	if pattern != "" {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	entries, err := c.client.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]os.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		if pattern != "" {
+			if ok, _ := path.Match(pattern, e.Name()); !ok {
+				continue
+			}
+		}
+		files = append(files, e)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name() < files[j].Name()
+	})
+
+	return files, nil
+}
+
+// Stat returns the file info of the remote path
+func (c *SFTPClient) Stat(p string) (os.FileInfo, error) {
+	return c.client.Stat(p)
+}
+
+// Rename moves oldname to newname, replacing newname if it already exists
+func (c *SFTPClient) Rename(oldname, newname string) error {
+	if _, ok := c.client.HasExtension(posixRenameExtension); ok {
+		return c.client.PosixRename(oldname, newname)
+	}
+
+	// plain SFTP rename fails when the target exists, so remove it first
+	if err := c.client.Remove(newname); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return c.client.Rename(oldname, newname)
+}
+
+// Remove deletes the remote file or empty directory
+func (c *SFTPClient) Remove(p string) error {
+	return c.client.Remove(p)
+}
+
+// MkdirAll creates dir along with any missing parents, like mkdir -p
+func (c *SFTPClient) MkdirAll(dir string) error {
+	return c.client.MkdirAll(dir)
+}
@@ -0,0 +1,188 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+var (
+	// ErrHostKeyMismatch is returned when the server presents a key different from the expected one
+	ErrHostKeyMismatch = errors.New("ssh: host key mismatch")
+
+	// ErrHostKeyVerificationNotConfigured is returned by the SFTP helpers when no
+	// host key callback has been set with SetDefaultHostKeyCallback
+	ErrHostKeyVerificationNotConfigured = errors.New("ssh: host key verification is not configured, call SetDefaultHostKeyCallback")
+)
+
+var (
+	defaultHostKeyCallback ssh.HostKeyCallback
+	hostKeyMu              sync.RWMutex
+)
+
+// SetDefaultHostKeyCallback sets the host key verification used by the SFTP helpers
+// that do not accept an *ssh.ClientConfig (UploadFileToSFTP, NewSFTPClientWithPassword, ...).
+// Until it is called every connection made by those helpers is refused.
+func SetDefaultHostKeyCallback(callback ssh.HostKeyCallback) {
+	hostKeyMu.Lock()
+	defer hostKeyMu.Unlock()
+
+	defaultHostKeyCallback = callback
+}
+
+// hostKeyCallback returns the configured default host key callback
+func hostKeyCallback() ssh.HostKeyCallback {
+	hostKeyMu.RLock()
+	defer hostKeyMu.RUnlock()
+
+	if defaultHostKeyCallback == nil {
+		return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			return ErrHostKeyVerificationNotConfigured
+		}
+	}
+
+	return defaultHostKeyCallback
+}
+
+// InsecureIgnoreHostKey accepts any host key. It must only be used as an explicit
+// opt-in, e.g. against local test servers, never for PHI transfers.
+func InsecureIgnoreHostKey() ssh.HostKeyCallback {
+	return ssh.InsecureIgnoreHostKey()
+}
+
+// KnownHostsHostKeyCallback verifies host keys against one or more OpenSSH known_hosts files
+func KnownHostsHostKeyCallback(files ...string) (ssh.HostKeyCallback, error) {
+	if len(files) == 0 {
+		return nil, errors.New("at least one known_hosts file is required")
+	}
+
+	return knownhosts.New(files...)
+}
+
+// FingerprintHostKeyCallback accepts only servers whose key matches one of the given
+// SHA256 fingerprints, as printed by `ssh-keygen -lf` (e.g. "SHA256:nThbg6kXUpJWGl7E1IGOCspRomTxdCARLviKw6E5SY8").
+// The "SHA256:" prefix is optional.
+func FingerprintHostKeyCallback(fingerprints ...string) ssh.HostKeyCallback {
+	pinned := make(map[string]bool, len(fingerprints))
+	for _, fp := range fingerprints {
+		pinned[normalizeFingerprint(fp)] = true
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		fp := ssh.FingerprintSHA256(key)
+		if !pinned[normalizeFingerprint(fp)] {
+			return fmt.Errorf("%w for %s: got %s", ErrHostKeyMismatch, hostname, fp)
+		}
+		return nil
+	}
+}
+
+func normalizeFingerprint(fp string) string {
+	fp = strings.TrimSpace(fp)
+	fp = strings.TrimPrefix(fp, "SHA256:")
+	return strings.TrimRight(fp, "=")
+}
+
+// HostKeyStore persists the host keys trusted by TrustOnFirstUseHostKeyCallback
+type HostKeyStore interface {
+	// Get returns the trusted key for host, or nil if the host has not been seen yet
+	Get(host string) (ssh.PublicKey, error)
+	// Put records key as the trusted key for host
+	Put(host string, key ssh.PublicKey) error
+}
+
+// TrustOnFirstUseHostKeyCallback trusts and records the key presented on the first
+// connection to a host and rejects any different key on later connections.
+func TrustOnFirstUseHostKeyCallback(store HostKeyStore) ssh.HostKeyCallback {
+	var mu sync.Mutex
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		host := knownhosts.Normalize(hostname)
+
+		trusted, err := store.Get(host)
+		if err != nil {
+			return err
+		}
+
+		if trusted == nil {
+			return store.Put(host, key)
+		}
+
+		if trusted.Type() != key.Type() || string(trusted.Marshal()) != string(key.Marshal()) {
+			return fmt.Errorf("%w for %s: expected %s, got %s", ErrHostKeyMismatch, hostname, ssh.FingerprintSHA256(trusted), ssh.FingerprintSHA256(key))
+		}
+
+		return nil
+	}
+}
+
+// FileHostKeyStore is a HostKeyStore backed by a file in known_hosts format,
+// so the same file can later be used with KnownHostsHostKeyCallback.
+type FileHostKeyStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileHostKeyStore returns a store persisting keys to path. The file is created on first write.
+func NewFileHostKeyStore(path string) *FileHostKeyStore {
+	return &FileHostKeyStore{path: path}
+}
+
+func (s *FileHostKeyStore) Get(host string) (ssh.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	host = knownhosts.Normalize(host)
+
+	for len(data) > 0 {
+		_, hosts, key, _, rest, err := ssh.ParseKnownHosts(data)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("unable to parse %s: %w", s.path, err)
+		}
+
+		for _, h := range hosts {
+			if h == host {
+				return key, nil
+			}
+		}
+		data = rest
+	}
+
+	return nil, nil
+}
+
+func (s *FileHostKeyStore) Put(host string, key ssh.PublicKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	_, err = f.WriteString(knownhosts.Line([]string{knownhosts.Normalize(host)}, key) + "\n")
+	return err
+}
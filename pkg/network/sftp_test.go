@@ -0,0 +1,195 @@
+package network
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/phil-inc/pcommon/pkg/internal/sftptest"
+	"golang.org/x/crypto/ssh"
+)
+
+func newTestSFTPClient(t *testing.T) *SFTPClient {
+	t.Helper()
+
+	address, hostKey := sftptest.NewServer(t)
+
+	client, err := NewSFTPClient(sftptest.ClientConfig(FingerprintHostKeyCallback(ssh.FingerprintSHA256(hostKey))), address)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func TestSFTPClient_UploadDownload(t *testing.T) {
+	client := newTestSFTPClient(t)
+	dir := t.TempDir()
+	dst := filepath.Join(dir, "claims.csv")
+
+	n, err := client.Upload(strings.NewReader("a,b,c\n"), dst)
+	if err != nil {
+		t.Fatalf("unexpected upload error: %v", err)
+	}
+	if n != 6 {
+		t.Errorf("expected 6 bytes uploaded, got %d", n)
+	}
+
+	var buf bytes.Buffer
+	if _, err := client.Download(dst, &buf); err != nil {
+		t.Fatalf("unexpected download error: %v", err)
+	}
+	if buf.String() != "a,b,c\n" {
+		t.Errorf("unexpected downloaded content %q", buf.String())
+	}
+
+	fi, err := client.Stat(dst)
+	if err != nil {
+		t.Fatalf("unexpected stat error: %v", err)
+	}
+	if fi.Size() != 6 {
+		t.Errorf("expected size 6, got %d", fi.Size())
+	}
+}
+
+func TestSFTPClient_UploadAtomic(t *testing.T) {
+	client := newTestSFTPClient(t)
+	dir := t.TempDir()
+	dst := filepath.Join(dir, "eligibility.txt")
+
+	if err := os.WriteFile(dst, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.UploadAtomic(strings.NewReader("new content"), dst); err != nil {
+		t.Fatalf("unexpected upload error: %v", err)
+	}
+
+	b, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "new content" {
+		t.Errorf("unexpected content %q", string(b))
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected temporary file to be renamed, found %d entries", len(entries))
+	}
+}
+
+func TestSFTPClient_ListRenameRemove(t *testing.T) {
+	client := newTestSFTPClient(t)
+	dir := t.TempDir()
+
+	for _, name := range []string{"b.csv", "a.csv", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := client.List(dir, "*.csv")
+	if err != nil {
+		t.Fatalf("unexpected list error: %v", err)
+	}
+	if len(files) != 2 || files[0].Name() != "a.csv" || files[1].Name() != "b.csv" {
+		t.Errorf("unexpected listing %v", files)
+	}
+
+	if _, err := client.List(dir, "[a-"); err == nil {
+		t.Errorf("expected error for malformed pattern")
+	}
+
+	archive := filepath.Join(dir, "archive", "2024", "01")
+	if err := client.MkdirAll(archive); err != nil {
+		t.Fatalf("unexpected mkdir error: %v", err)
+	}
+
+	moved := filepath.Join(archive, "a.csv")
+	if err := client.Rename(filepath.Join(dir, "a.csv"), moved); err != nil {
+		t.Fatalf("unexpected rename error: %v", err)
+	}
+	if _, err := os.Stat(moved); err != nil {
+		t.Errorf("expected renamed file to exist: %v", err)
+	}
+
+	if err := client.Remove(moved); err != nil {
+		t.Fatalf("unexpected remove error: %v", err)
+	}
+	if _, err := client.Stat(moved); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected not exist error, got %v", err)
+	}
+}
+
+func TestSFTPClient_DefaultHostKeyCallbackRefuses(t *testing.T) {
+	address, _ := sftptest.NewServer(t)
+
+	// the ssh handshake does not wrap callback errors, so compare messages
+	_, err := NewSFTPClientWithPassword(sftptest.User, sftptest.Password, address)
+	if err == nil || !strings.Contains(err.Error(), ErrHostKeyVerificationNotConfigured.Error()) {
+		t.Errorf("expected ErrHostKeyVerificationNotConfigured, got %v", err)
+	}
+}
+
+func TestFingerprintHostKeyCallback(t *testing.T) {
+	address, hostKey := sftptest.NewServer(t)
+
+	fp := strings.TrimPrefix(ssh.FingerprintSHA256(hostKey), "SHA256:")
+	client, err := NewSFTPClient(sftptest.ClientConfig(FingerprintHostKeyCallback(fp)), address)
+	if err != nil {
+		t.Fatalf("expected pinned fingerprint without prefix to be accepted: %v", err)
+	}
+	client.Close()
+
+	err = FingerprintHostKeyCallback("SHA256:bm90LXRoZS1yaWdodC1rZXk")(address, nil, hostKey)
+	if !errors.Is(err, ErrHostKeyMismatch) {
+		t.Errorf("expected ErrHostKeyMismatch, got %v", err)
+	}
+}
+
+func TestTrustOnFirstUseHostKeyCallback(t *testing.T) {
+	address, hostKey := sftptest.NewServer(t)
+	knownHostsFile := filepath.Join(t.TempDir(), "known_hosts")
+	store := NewFileHostKeyStore(knownHostsFile)
+
+	// first connection records the key
+	client, err := NewSFTPClient(sftptest.ClientConfig(TrustOnFirstUseHostKeyCallback(store)), address)
+	if err != nil {
+		t.Fatalf("unexpected error on first use: %v", err)
+	}
+	client.Close()
+
+	trusted, err := store.Get(address)
+	if err != nil {
+		t.Fatalf("unexpected store error: %v", err)
+	}
+	if trusted == nil || ssh.FingerprintSHA256(trusted) != ssh.FingerprintSHA256(hostKey) {
+		t.Fatalf("expected host key to be persisted, got %v", trusted)
+	}
+
+	// the persisted file is a valid known_hosts file
+	callback, err := KnownHostsHostKeyCallback(knownHostsFile)
+	if err != nil {
+		t.Fatalf("unexpected known_hosts error: %v", err)
+	}
+	client, err = NewSFTPClient(sftptest.ClientConfig(callback), address)
+	if err != nil {
+		t.Fatalf("expected known_hosts verification to succeed: %v", err)
+	}
+	client.Close()
+
+	// a server presenting another key on the same address is rejected
+	_, otherKey := sftptest.NewServer(t)
+	err = TrustOnFirstUseHostKeyCallback(store)(address, nil, otherKey)
+	if !errors.Is(err, ErrHostKeyMismatch) {
+		t.Errorf("expected ErrHostKeyMismatch, got %v", err)
+	}
+}
@@ -0,0 +1,296 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phil-inc/pcommon/pkg/internal/watch"
+	logger "github.com/phil-inc/plog-ng/pkg/core"
+)
+
+const (
+	// DefaultWatchPollInterval is used when SFTPWatcherConfig.PollInterval is not set
+	DefaultWatchPollInterval = 5 * time.Minute
+)
+
+// WatchedFile describes a remote file picked up by SFTPWatcher
+type WatchedFile struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// Name returns the base name of the file
+func (f WatchedFile) Name() string {
+	return path.Base(f.Path)
+}
+
+func (f WatchedFile) sameAs(o WatchedFile) bool {
+	return f.Size == o.Size && f.ModTime.Equal(o.ModTime)
+}
+
+// WatchStateStore records the files already handled by SFTPWatcher so that
+// they are not processed again after a restart
+type WatchStateStore interface {
+	// Get returns the last processed state of the file at path, if any
+	Get(ctx context.Context, path string) (*WatchedFile, error)
+	// Put records file as processed
+	Put(ctx context.Context, file WatchedFile) error
+}
+
+// SFTPFileHandler processes a file found by SFTPWatcher. r streams the remote content.
+// When an error is returned the file is neither recorded nor archived and will be retried on the next poll.
+type SFTPFileHandler func(ctx context.Context, file WatchedFile, r io.Reader) error
+
+// SFTPWatcherConfig configures SFTPWatcher
+type SFTPWatcherConfig struct {
+	// Dir is the remote directory to watch
+	Dir string
+	// Pattern optionally filters files by name using path.Match syntax, e.g. "*.csv"
+	Pattern string
+	// PollInterval is the time between two listings, defaults to DefaultWatchPollInterval
+	PollInterval time.Duration
+	// StableFor is how long a file must keep the same size and modification time
+	// before it is handled, so files still being written by the partner are skipped.
+	// Zero handles files as soon as they are seen.
+	StableFor time.Duration
+	// ArchiveDir optionally receives processed files, it is created if missing. Files are
+	// renamed after their modification time, e.g. eligibility.20240301T101500.csv, so that a
+	// reused name never replaces an earlier archive.
+	ArchiveDir string
+	// Store keeps track of processed files, defaults to an in memory store
+	Store WatchStateStore
+	// OnError is called with errors that do not stop the watcher, defaults to logging them
+	OnError func(err error)
+}
+
+type pendingFile struct {
+	file      WatchedFile
+	firstSeen time.Time
+}
+
+// SFTPWatcher periodically lists a remote directory and hands new or changed files to a handler
+type SFTPWatcher struct {
+	client  *SFTPClient
+	config  SFTPWatcherConfig
+	handler SFTPFileHandler
+	pending map[string]pendingFile
+	now     func() time.Time
+}
+
+// NewSFTPWatcher creates a watcher using an already connected client
+func NewSFTPWatcher(client *SFTPClient, config SFTPWatcherConfig, handler SFTPFileHandler) *SFTPWatcher {
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultWatchPollInterval
+	}
+
+	if config.Store == nil {
+		config.Store = NewMemoryWatchStateStore()
+	}
+
+	if config.OnError == nil {
+		config.OnError = func(err error) {
+			logger.Errorf("[SFTP][WATCHER] %s", err.Error())
+		}
+	}
+
+	return &SFTPWatcher{
+		client:  client,
+		config:  config,
+		handler: handler,
+		pending: map[string]pendingFile{},
+		now:     time.Now,
+	}
+}
+
+// Run polls the remote directory until ctx is cancelled
+func (w *SFTPWatcher) Run(ctx context.Context) error {
+	return watch.Run(ctx, w.config.PollInterval, w.Poll, w.config.OnError)
+}
+
+// Poll lists the remote directory once and handles every new or changed file that is stable
+func (w *SFTPWatcher) Poll(ctx context.Context) error {
+	entries, err := w.client.List(w.config.Dir, w.config.Pattern)
+	if err != nil {
+		return fmt.Errorf("unable to list %s: %w", w.config.Dir, err)
+	}
+
+	seen := map[string]bool{}
+	var errs []error
+
+	for _, e := range entries {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if e.IsDir() {
+			continue
+		}
+
+		file := WatchedFile{
+			Path:    path.Join(w.config.Dir, e.Name()),
+			Size:    e.Size(),
+			ModTime: e.ModTime(),
+		}
+		seen[file.Path] = true
+
+		processed, err := w.config.Store.Get(ctx, file.Path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if processed != nil && processed.sameAs(file) {
+			delete(w.pending, file.Path)
+			continue
+		}
+
+		if !w.isStable(file) {
+			continue
+		}
+
+		if err := w.process(ctx, file); err != nil {
+			errs = append(errs, fmt.Errorf("unable to process %s: %w", file.Path, err))
+		}
+	}
+
+	// forget files that disappeared before becoming stable
+	for p := range w.pending {
+		if !seen[p] {
+			delete(w.pending, p)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// isStable reports whether the file has kept the same size and modification time for StableFor
+func (w *SFTPWatcher) isStable(file WatchedFile) bool {
+	if w.config.StableFor <= 0 {
+		return true
+	}
+
+	p, ok := w.pending[file.Path]
+	if !ok || !p.file.sameAs(file) {
+		w.pending[file.Path] = pendingFile{file: file, firstSeen: w.now()}
+		return false
+	}
+
+	return w.now().Sub(p.firstSeen) >= w.config.StableFor
+}
+
+func (w *SFTPWatcher) process(ctx context.Context, file WatchedFile) error {
+	sf, err := w.client.client.Open(file.Path)
+	if err != nil {
+		return err
+	}
+
+	err = w.handler(ctx, file, sf)
+	sf.Close()
+	if err != nil {
+		return err
+	}
+
+	delete(w.pending, file.Path)
+
+	// archive before recording the file as processed, a file recorded but left in Dir
+	// would never be archived
+	if w.config.ArchiveDir != "" {
+		if err := w.client.MkdirAll(w.config.ArchiveDir); err != nil {
+			return err
+		}
+
+		archived, err := w.archivePath(file)
+		if err != nil {
+			return err
+		}
+
+		if err := w.client.Rename(file.Path, archived); err != nil {
+			return err
+		}
+	}
+
+	return w.config.Store.Put(ctx, file)
+}
+
+// archivePath returns a free path in ArchiveDir for file, named after its modification time so that
+// a partner reusing a file name does not replace an earlier archived file, e.g.
+// eligibility.20240301T101500.csv, followed by a counter if that name is taken as well
+func (w *SFTPWatcher) archivePath(file WatchedFile) (string, error) {
+	ext := path.Ext(file.Name())
+	stem := strings.TrimSuffix(file.Name(), ext) + "." + file.ModTime.UTC().Format("20060102T150405")
+
+	for i := 0; ; i++ {
+		name := stem + ext
+		if i > 0 {
+			name = fmt.Sprintf("%s-%d%s", stem, i, ext)
+		}
+
+		p := path.Join(w.config.ArchiveDir, name)
+		if _, err := w.client.Stat(p); errors.Is(err, os.ErrNotExist) {
+			return p, nil
+		} else if err != nil {
+			return "", err
+		}
+	}
+}
+
+// MemoryWatchStateStore keeps the watcher state in memory, it is lost on restart
+type MemoryWatchStateStore struct {
+	files map[string]WatchedFile
+	mu    sync.Mutex
+}
+
+func NewMemoryWatchStateStore() *MemoryWatchStateStore {
+	return &MemoryWatchStateStore{files: map[string]WatchedFile{}}
+}
+
+func (s *MemoryWatchStateStore) Get(ctx context.Context, path string) (*WatchedFile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.files[path]
+	if !ok {
+		return nil, nil
+	}
+
+	return &f, nil
+}
+
+func (s *MemoryWatchStateStore) Put(ctx context.Context, file WatchedFile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.files[file.Path] = file
+	return nil
+}
+
+// FileWatchStateStore persists the watcher state as JSON in a local file
+type FileWatchStateStore struct {
+	file *watch.JSONFile[WatchedFile]
+}
+
+// NewFileWatchStateStore returns a store persisted to path. The file is created on first write.
+func NewFileWatchStateStore(path string) *FileWatchStateStore {
+	return &FileWatchStateStore{file: watch.NewJSONFile[WatchedFile](path)}
+}
+
+func (s *FileWatchStateStore) Get(ctx context.Context, path string) (*WatchedFile, error) {
+	f, ok, err := s.file.Get(path)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	return &f, nil
+}
+
+func (s *FileWatchStateStore) Put(ctx context.Context, file WatchedFile) error {
+	return s.file.Put(file.Path, file)
+}
@@ -0,0 +1,188 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSFTPWatcher_Poll(t *testing.T) {
+	client := newTestSFTPClient(t)
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "processed")
+
+	if err := os.WriteFile(filepath.Join(dir, "eligibility.csv"), []byte("id,name\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "readme.txt"), []byte("ignored"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Date(2024, 3, 1, 10, 15, 0, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(dir, "eligibility.csv"), modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	handled := map[string]string{}
+	config := SFTPWatcherConfig{
+		Dir:        dir,
+		Pattern:    "*.csv",
+		StableFor:  time.Minute,
+		ArchiveDir: archive,
+	}
+	watcher := NewSFTPWatcher(client, config, func(ctx context.Context, file WatchedFile, r io.Reader) error {
+		b, err := io.ReadAll(r)
+		handled[file.Name()] = string(b)
+		return err
+	})
+
+	now := time.Now()
+	watcher.now = func() time.Time { return now }
+
+	// first poll only records the file as pending
+	if err := watcher.Poll(context.Background()); err != nil {
+		t.Fatalf("unexpected poll error: %v", err)
+	}
+	if len(handled) != 0 {
+		t.Fatalf("expected no file to be handled before it is stable, got %v", handled)
+	}
+
+	now = now.Add(time.Minute)
+	if err := watcher.Poll(context.Background()); err != nil {
+		t.Fatalf("unexpected poll error: %v", err)
+	}
+
+	if len(handled) != 1 || handled["eligibility.csv"] != "id,name\n" {
+		t.Fatalf("unexpected handled files %v", handled)
+	}
+
+	if _, err := os.Stat(filepath.Join(archive, "eligibility.20240301T101500.csv")); err != nil {
+		t.Errorf("expected file to be archived: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "readme.txt")); err != nil {
+		t.Errorf("expected non matching file to be left alone: %v", err)
+	}
+
+	// the partner drops a new file under the same name and modification time
+	if err := os.WriteFile(filepath.Join(dir, "eligibility.csv"), []byte("id,name\n1,Acme\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filepath.Join(dir, "eligibility.csv"), modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		now = now.Add(time.Minute)
+		if err := watcher.Poll(context.Background()); err != nil {
+			t.Fatalf("unexpected poll error: %v", err)
+		}
+	}
+
+	for name, content := range map[string]string{
+		"eligibility.20240301T101500.csv":   "id,name\n",
+		"eligibility.20240301T101500-1.csv": "id,name\n1,Acme\n",
+	} {
+		if b, err := os.ReadFile(filepath.Join(archive, name)); err != nil || string(b) != content {
+			t.Errorf("expected %s to hold %q, got %q, %v", name, content, b, err)
+		}
+	}
+}
+
+func TestSFTPWatcher_SkipsProcessedAndRetriesFailures(t *testing.T) {
+	client := newTestSFTPClient(t)
+	dir := t.TempDir()
+	store := NewFileWatchStateStore(filepath.Join(t.TempDir(), "state.json"))
+
+	if err := os.WriteFile(filepath.Join(dir, "claims.csv"), []byte("1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	fail := true
+	watcher := NewSFTPWatcher(client, SFTPWatcherConfig{Dir: dir, Store: store}, func(ctx context.Context, file WatchedFile, r io.Reader) error {
+		calls++
+		if fail {
+			return errors.New("handler failed")
+		}
+		return nil
+	})
+
+	if err := watcher.Poll(context.Background()); err == nil {
+		t.Fatalf("expected handler error to be returned")
+	}
+
+	fail = false
+	if err := watcher.Poll(context.Background()); err != nil {
+		t.Fatalf("unexpected poll error: %v", err)
+	}
+	if err := watcher.Poll(context.Background()); err != nil {
+		t.Fatalf("unexpected poll error: %v", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("expected handler to be called twice, got %d", calls)
+	}
+
+	// a changed file is handled again
+	if err := os.WriteFile(filepath.Join(dir, "claims.csv"), []byte("12"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := watcher.Poll(context.Background()); err != nil {
+		t.Fatalf("unexpected poll error: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected changed file to be handled, got %d calls", calls)
+	}
+
+	state, err := store.Get(context.Background(), filepath.Join(dir, "claims.csv"))
+	if err != nil || state == nil || state.Size != 2 {
+		t.Errorf("expected persisted state with size 2, got %v, %v", state, err)
+	}
+}
+
+func TestSFTPWatcher_RecordsOnlyArchivedFiles(t *testing.T) {
+	client := newTestSFTPClient(t)
+	dir := t.TempDir()
+	archive := filepath.Join(t.TempDir(), "processed")
+	store := NewMemoryWatchStateStore()
+
+	if err := os.WriteFile(filepath.Join(dir, "claims.csv"), []byte("1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// a file in place of the archive directory makes archiving fail
+	if err := os.WriteFile(archive, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	watcher := NewSFTPWatcher(client, SFTPWatcherConfig{Dir: dir, Store: store, ArchiveDir: archive}, func(ctx context.Context, file WatchedFile, r io.Reader) error {
+		calls++
+		return nil
+	})
+
+	if err := watcher.Poll(context.Background()); err == nil {
+		t.Fatalf("expected archive error to be returned")
+	}
+	if state, _ := store.Get(context.Background(), filepath.Join(dir, "claims.csv")); state != nil {
+		t.Fatalf("expected a file left in the directory not to be recorded, got %v", state)
+	}
+
+	if err := os.Remove(archive); err != nil {
+		t.Fatal(err)
+	}
+	if err := watcher.Poll(context.Background()); err != nil {
+		t.Fatalf("unexpected poll error: %v", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("expected the file to be handled again, got %d calls", calls)
+	}
+	if archived, _ := filepath.Glob(filepath.Join(archive, "claims.*.csv")); len(archived) != 1 {
+		t.Errorf("expected file to be archived, got %v", archived)
+	}
+	if state, _ := store.Get(context.Background(), filepath.Join(dir, "claims.csv")); state == nil {
+		t.Errorf("expected the archived file to be recorded")
+	}
+}
@@ -0,0 +1,257 @@
+package internal
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// maxParams is the limit of bind parameters of a Postgres statement
+const maxParams = 65535
+
+// statement is a query with its arguments
+type statement struct {
+	query  string
+	args   []interface{}
+	models []interface{} // models inserted, for the AfterInsert hooks
+}
+
+// InsertMany inserts models, a slice of structs, with multi-row INSERT statements of less than
+// 65535 arguments. Consecutive models inserting the same columns share statements, so models whose
+// omitempty fields differ are inserted separately. When more than one statement is needed they
+// run in a transaction, or a savepoint inside one, so a failure inserts nothing.
+func (qb *QueryBuilderImpl) InsertMany(models interface{}) (Result, error) {
+	statements, err := qb.insertStatements(models)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if len(statements) == 1 {
+		result, err := qb.execute(statements[0].query, statements[0].args)
+		if err != nil {
+			return Result{}, err
+		}
+		return result, afterInsert(statements[0].models)
+	}
+
+	var total Result
+	err = qb.Transaction(qb.context(), func(tx QueryBuilder) error {
+		// keep the table and RETURNING clause of qb on the executor of the transaction
+		t := tx.(*QueryBuilderImpl)
+		q := qb.clone()
+		q.db, q.depth = t.db, t.depth
+
+		for _, s := range statements {
+			result, err := q.execute(s.query, s.args)
+			if err != nil {
+				return err
+			}
+			total.RowsAffected += result.RowsAffected
+			total.Rows = append(total.Rows, result.Rows...)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	if len(total.Rows) > 0 {
+		total.Returning = total.Rows[0]
+	}
+
+	for _, s := range statements {
+		if err := afterInsert(s.models); err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+func (qb *QueryBuilderImpl) insertStatements(models interface{}) ([]statement, error) {
+	if qb.err != nil {
+		return nil, qb.err
+	}
+
+	v := reflect.ValueOf(models)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("cannot insert %T, expected a slice of models", models)
+	}
+	if v.Len() == 0 {
+		return nil, fmt.Errorf("no models to insert")
+	}
+
+	var statements []statement
+	var columns, quoted []string
+	var rows [][]interface{}
+	var rowModels []interface{}
+
+	flush := func() {
+		for len(rows) > 0 {
+			n := min(len(rows), maxParams/len(columns))
+			s := multiRowInsert(qb.tableName, quoted, rows[:n])
+			s.models = rowModels[:n]
+			statements = append(statements, s)
+			rows, rowModels = rows[n:], rowModels[n:]
+		}
+	}
+
+	for i := 0; i < v.Len(); i++ {
+		model, err := beforeInsert(v.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+
+		rowColumns, values, _ := extractColumnsAndValues(model)
+		if len(rowColumns) == 0 {
+			return nil, fmt.Errorf("no valid fields to insert in model %d", i)
+		}
+
+		if !slices.Equal(rowColumns, columns) {
+			flush()
+
+			var err error
+			if quoted, err = quoteIdents(rowColumns); err != nil {
+				return nil, err
+			}
+			columns = rowColumns
+		}
+		rows = append(rows, values)
+		rowModels = append(rowModels, model)
+	}
+	flush()
+
+	return statements, nil
+}
+
+func multiRowInsert(table string, columns []string, rows [][]interface{}) statement {
+	var b strings.Builder
+	args := make([]interface{}, 0, len(rows)*len(columns))
+
+	fmt.Fprintf(&b, "INSERT INTO %s (%s) VALUES ", table, strings.Join(columns, ", "))
+	for i, row := range rows {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString("(")
+		for j, value := range row {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			args = append(args, value)
+			fmt.Fprintf(&b, "$%d", len(args))
+		}
+		b.WriteString(")")
+	}
+
+	return statement{query: b.String(), args: args}
+}
+
+// Upsert inserts model, on a conflict on conflictColumns the updateColumns are set from the
+// proposed row, e.g. Upsert(user, []string{"email"}, "name"). Without updateColumns the
+// existing row is left as is (DO NOTHING) and conflictColumns may be empty.
+func (qb *QueryBuilderImpl) Upsert(model interface{}, conflictColumns []string, updateColumns ...string) (Result, error) {
+	s, err := qb.upsertStatement(model, conflictColumns, updateColumns)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result, err := qb.execute(s.query, s.args)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return result, afterInsert(s.models)
+}
+
+func (qb *QueryBuilderImpl) upsertStatement(model interface{}, conflictColumns, updateColumns []string) (statement, error) {
+	insert, err := qb.insertStatement(model)
+	if err != nil {
+		return statement{}, err
+	}
+
+	conflictColumns, err = quoteIdents(conflictColumns)
+	if err != nil {
+		return statement{}, err
+	}
+
+	// a conflicting row that is updated gets the new `updated` timestamps of model
+	if len(updateColumns) > 0 {
+		for _, column := range updatedColumns(model) {
+			if !slices.Contains(updateColumns, column) {
+				updateColumns = append(slices.Clip(updateColumns), column)
+			}
+		}
+	}
+
+	updateColumns, err = quoteIdents(updateColumns)
+	if err != nil {
+		return statement{}, err
+	}
+
+	query := insert.query + " ON CONFLICT"
+	values := insert.args
+
+	if len(conflictColumns) > 0 {
+		query += fmt.Sprintf(" (%s)", strings.Join(conflictColumns, ", "))
+	}
+
+	if len(updateColumns) == 0 {
+		return statement{query: query + " DO NOTHING", args: values, models: insert.models}, nil
+	}
+
+	if len(conflictColumns) == 0 {
+		return statement{}, fmt.Errorf("conflict columns are required to update on conflict")
+	}
+
+	setClauses := make([]string, len(updateColumns))
+	for i, column := range updateColumns {
+		setClauses[i] = fmt.Sprintf("%s = EXCLUDED.%s", column, column)
+	}
+
+	return statement{query: query + " DO UPDATE SET " + strings.Join(setClauses, ", "), args: values, models: insert.models}, nil
+}
+
+// UpsertInto upserts model into its table like Upsert and returns the inserted or updated row.
+// It returns sql.ErrNoRows when the row was left as is because no updateColumns are given.
+func UpsertInto[T Model](qb QueryBuilder, model T, conflictColumns []string, updateColumns ...string) (T, error) {
+	var zero T
+
+	impl, ok := qb.Table(model).(*QueryBuilderImpl)
+	if !ok {
+		return zero, fmt.Errorf("unsupported query builder %T", qb)
+	}
+
+	s, err := impl.upsertStatement(model, conflictColumns, updateColumns)
+	if err != nil {
+		return zero, err
+	}
+
+	rows, err := impl.db.QueryContext(impl.context(), s.query+" RETURNING *", s.args...)
+	if err != nil {
+		return zero, err
+	}
+	defer rows.Close()
+
+	scanner, err := newRowScanner[T](rows)
+	if err != nil {
+		return zero, err
+	}
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return zero, fmt.Errorf("row iteration error: %w", err)
+		}
+		return zero, sql.ErrNoRows
+	}
+
+	item, err := scanner.scan(rows)
+	if err != nil {
+		return zero, err
+	}
+
+	return item, afterInsert(s.models)
+}
@@ -0,0 +1,240 @@
+package internal
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+var valuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
+// fieldInfo is a struct field mapped to a column by its `db` tag, `db:"name,option,..."` with the options
+//
+//	omitempty  not written when zero
+//	pk         primary key, used by WherePK, not written when zero nor by Set
+//	readonly   never written, e.g. generated columns
+//	default    not written when zero so the database default applies
+//	json       stored as json, e.g. in a jsonb column
+//	created    set to the current time on insert when zero, never written by Set
+//	updated    set to the current time on insert and by Set
+//	softdelete deletion time, Delete sets it instead of removing the row and selects skip the
+//	           rows where it is not NULL, so it must be nullable, e.g. *time.Time. Never written by Set.
+//
+// `db:"-"` ignores the field and the fields of embedded structs without tag are flattened.
+type fieldInfo struct {
+	column     string
+	index      []int
+	omitEmpty  bool
+	pk         bool
+	readonly   bool
+	dbDefault  bool
+	json       bool
+	created    bool
+	updated    bool
+	softDelete bool
+}
+
+// fieldUse selects the fields of a model written by a statement
+type fieldUse int
+
+const (
+	forInsert fieldUse = iota
+	forUpdate
+	forFilter
+)
+
+// modelFields returns the fields of struct type t mapped to columns, in declaration order.
+// A field of an embedded struct is dropped when a shallower field has the same column.
+func modelFields(t reflect.Type) []fieldInfo {
+	fields := collectFields(t, nil)
+
+	depth := map[string]int{}
+	for _, f := range fields {
+		if d, ok := depth[f.column]; !ok || len(f.index) < d {
+			depth[f.column] = len(f.index)
+		}
+	}
+
+	visible := make([]fieldInfo, 0, len(fields))
+	for _, f := range fields {
+		if len(f.index) == depth[f.column] {
+			visible = append(visible, f)
+			depth[f.column] = -1 // keep the first one only
+		}
+	}
+
+	return visible
+}
+
+func collectFields(t reflect.Type, parent []int) []fieldInfo {
+	fields := []fieldInfo{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("db")
+		index := append(append([]int(nil), parent...), i)
+
+		if tag == "-" {
+			continue
+		}
+
+		if tag == "" {
+			if embedded := embeddedStruct(field); embedded != nil {
+				fields = append(fields, collectFields(embedded, index)...)
+			}
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		info := fieldInfo{column: parts[0], index: index}
+		for _, option := range parts[1:] {
+			switch strings.TrimSpace(option) {
+			case "omitempty":
+				info.omitEmpty = true
+			case "pk":
+				info.pk = true
+			case "readonly":
+				info.readonly = true
+			case "default":
+				info.dbDefault = true
+			case "json":
+				info.json = true
+			case "created":
+				info.created = true
+			case "updated":
+				info.updated = true
+			case "softdelete":
+				info.softDelete = true
+			}
+		}
+
+		fields = append(fields, info)
+	}
+
+	return fields
+}
+
+// embeddedStruct returns the type of an embedded struct whose fields are flattened, nil otherwise
+func embeddedStruct(field reflect.StructField) reflect.Type {
+	if !field.Anonymous {
+		return nil
+	}
+
+	t := field.Type
+	if t.Kind() == reflect.Ptr {
+		if !field.IsExported() {
+			return nil // cannot be read through reflection
+		}
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || t == timeType {
+		return nil
+	}
+
+	return t
+}
+
+// modelColumns returns the columns written by use and their values. Zero values are skipped
+// for omitempty, pk and default fields, and for every field when filtering.
+func modelColumns(model interface{}, use fieldUse) ([]string, []interface{}) {
+	v := reflect.Indirect(reflect.ValueOf(model))
+	if v.Kind() != reflect.Struct {
+		return nil, nil
+	}
+
+	columns := []string{}
+	values := []interface{}{}
+
+	for _, f := range modelFields(v.Type()) {
+		// the soft delete column is only written by Delete, so that Set cannot restore a deleted row
+		if use != forFilter && f.readonly || use == forUpdate && (f.pk || f.created && !f.updated || f.softDelete) {
+			continue
+		}
+
+		field, err := v.FieldByIndexErr(f.index)
+		if err != nil {
+			continue // field of a nil embedded pointer
+		}
+
+		skipZero := use == forFilter || f.omitEmpty || f.pk || f.dbDefault
+		if skipZero && isZeroValue(field) {
+			continue
+		}
+
+		columns = append(columns, f.column)
+		values = append(values, columnValue(f, field))
+	}
+
+	return columns, values
+}
+
+// columnValue returns the value bound for the field, json fields are marshalled
+func columnValue(f fieldInfo, field reflect.Value) interface{} {
+	if f.json || isJSONType(field.Type()) && !field.Type().Implements(valuerType) {
+		return jsonValue{field.Interface()}
+	}
+	return field.Interface()
+}
+
+// jsonValue binds a value as json text, nil pointers, maps and slices as NULL
+type jsonValue struct {
+	v interface{}
+}
+
+func (j jsonValue) Value() (driver.Value, error) {
+	v := reflect.ValueOf(j.v)
+	if !v.IsValid() || (v.Kind() == reflect.Ptr || v.Kind() == reflect.Map || v.Kind() == reflect.Slice) && v.IsNil() {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(j.v)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(data), nil
+}
+
+// updatedColumns returns the `updated` columns of the model type
+func updatedColumns(model interface{}) []string {
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	columns := []string{}
+	for _, f := range modelFields(t) {
+		if f.updated {
+			columns = append(columns, f.column)
+		}
+	}
+
+	return columns
+}
+
+// columnNames returns every column of the model type
+func columnNames(model interface{}) []string {
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	names := []string{}
+	for _, f := range modelFields(t) {
+		names = append(names, f.column)
+	}
+
+	return names
+}
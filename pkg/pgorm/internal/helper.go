@@ -3,41 +3,15 @@ package internal
 import (
 	"fmt"
 	"reflect"
-	"strings"
 )
 
+// extractColumnsAndValues returns the columns inserted for model, their values and placeholders
 func extractColumnsAndValues(model interface{}) ([]string, []interface{}, []string) {
-	t := reflect.TypeOf(model)
-	v := reflect.ValueOf(model)
+	columns, values := modelColumns(model, forInsert)
 
-	columns := []string{}
-	values := []interface{}{}
-	placeholders := []string{}
-
-	placeholderCounter := 1
-
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		tag := field.Tag.Get("db")
-
-		if tag == "" {
-			continue
-		}
-
-		tagParts := strings.Split(tag, ",")
-		columnName := tagParts[0]
-		omitEmpty := len(tagParts) > 1 && tagParts[1] == "omitempty"
-
-		fieldValue := v.Field(i)
-
-		if omitEmpty && isZeroValue(fieldValue) {
-			continue
-		}
-
-		columns = append(columns, columnName)
-		values = append(values, fieldValue.Interface())
-		placeholders = append(placeholders, fmt.Sprintf("$%d", placeholderCounter))
-		placeholderCounter++
+	placeholders := make([]string, len(columns))
+	for i := range placeholders {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
 	}
 
 	return columns, values, placeholders
@@ -68,9 +42,9 @@ func isZeroValue(value reflect.Value) bool {
 	case reflect.Slice, reflect.Map, reflect.Chan:
 		return value.IsNil() || value.Len() == 0
 	case reflect.Struct:
-		if value.Type().String() == "time.Time" {
-			// Special handling for time.Time zero value
-			return value.Interface() == reflect.Zero(value.Type()).Interface()
+		if value.Type() == timeType || value.Type().Implements(valuerType) {
+			// time.Time and Valuers such as decimals are opaque, their fields may be unexported
+			return value.IsZero()
 		}
 		// Check if all fields in the struct are zero
 		for i := 0; i < value.NumField(); i++ {
@@ -80,6 +54,10 @@ func isZeroValue(value reflect.Value) bool {
 		}
 		return true
 	default:
+		if !value.CanInterface() {
+			// Unexported fields cannot be read through Interface
+			return value.IsZero()
+		}
 		// Compare with the zero value of the same type
 		zeroValue := reflect.Zero(value.Type())
 		return reflect.DeepEqual(value.Interface(), zeroValue.Interface())
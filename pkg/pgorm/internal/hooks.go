@@ -0,0 +1,149 @@
+package internal
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Hooks are optional methods of models called by the builder, an error cancels the operation.
+// Implement them on the pointer receiver to change the model, the builder works on a copy of
+// models passed by value.
+type (
+	// BeforeInserter is called before the model is inserted, by Insert, InsertMany and Upsert
+	BeforeInserter interface {
+		BeforeInsert() error
+	}
+
+	// AfterInserter is called once the model is inserted
+	AfterInserter interface {
+		AfterInsert() error
+	}
+
+	// BeforeUpdater is called when the model is given to Set
+	BeforeUpdater interface {
+		BeforeUpdate() error
+	}
+
+	// AfterFinder is called on every row scanned into the model
+	AfterFinder interface {
+		AfterFind() error
+	}
+
+	// Validator is called before the model is inserted or given to Set, after the before hooks
+	Validator interface {
+		Validate() error
+	}
+)
+
+// now returns the time of the created and updated timestamps
+var now = time.Now
+
+// beforeInsert runs the insert hooks of model and sets its created and updated timestamps,
+// returning the model to insert
+func beforeInsert(model interface{}) (interface{}, error) {
+	m := addressable(model)
+
+	if h, ok := m.(BeforeInserter); ok {
+		if err := h.BeforeInsert(); err != nil {
+			return nil, fmt.Errorf("before insert hook failed: %w", err)
+		}
+	}
+
+	setTimestamps(m, true)
+
+	return m, validate(m)
+}
+
+// beforeUpdate runs the update hooks of model and sets its updated timestamps,
+// returning the model to write
+func beforeUpdate(model interface{}) (interface{}, error) {
+	m := addressable(model)
+
+	if h, ok := m.(BeforeUpdater); ok {
+		if err := h.BeforeUpdate(); err != nil {
+			return nil, fmt.Errorf("before update hook failed: %w", err)
+		}
+	}
+
+	setTimestamps(m, false)
+
+	return m, validate(m)
+}
+
+func afterInsert(models []interface{}) error {
+	for _, m := range models {
+		if h, ok := m.(AfterInserter); ok {
+			if err := h.AfterInsert(); err != nil {
+				return fmt.Errorf("after insert hook failed: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func afterFind(item interface{}) error {
+	if h, ok := item.(AfterFinder); ok {
+		if err := h.AfterFind(); err != nil {
+			return fmt.Errorf("after find hook failed: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func validate(model interface{}) error {
+	if v, ok := model.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return fmt.Errorf("validation failed: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// addressable returns model when it is a pointer, a pointer to a copy of it otherwise
+func addressable(model interface{}) interface{} {
+	v := reflect.ValueOf(model)
+	if !v.IsValid() || v.Kind() == reflect.Ptr {
+		return model
+	}
+
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	return p.Interface()
+}
+
+// setTimestamps sets the `updated` fields of model to now, and on insert the zero `created` fields.
+// Fields are time.Time or *time.Time.
+func setTimestamps(model interface{}, insert bool) {
+	v := reflect.ValueOf(model)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return
+	}
+	v = v.Elem()
+
+	t := now()
+	for _, f := range modelFields(v.Type()) {
+		if !f.updated && !(insert && f.created) {
+			continue
+		}
+
+		field, err := v.FieldByIndexErr(f.index)
+		if err != nil || !field.CanSet() {
+			continue
+		}
+
+		if f.created && !f.updated && !isZeroValue(field) {
+			continue // keep a creation time set by the caller
+		}
+
+		switch field.Type() {
+		case timeType:
+			field.Set(reflect.ValueOf(t))
+		case reflect.PointerTo(timeType):
+			field.Set(reflect.ValueOf(&t))
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrUnsafeIdentifier is returned for a table or column name that is not a plain identifier
+	ErrUnsafeIdentifier = errors.New("unsafe identifier")
+
+	// ErrMissingWhere is returned by Update and Delete without conditions, unless AllowAllRows is set
+	ErrMissingWhere = errors.New("update or delete without a WHERE clause")
+)
+
+// maxIdentifierLength is the length Postgres truncates identifiers to
+const maxIdentifierLength = 63
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*$`)
+
+// quoteIdent validates name, optionally qualified as schema.table or table.column,
+// and double quotes every part, e.g. public.user becomes "public"."user". Parts are folded
+// to lower case first, so that names resolve as they did unquoted, e.g. createdAt => "createdat".
+func quoteIdent(name string) (string, error) {
+	parts := strings.Split(name, ".")
+	if len(parts) > 3 {
+		return "", fmt.Errorf("%w: %q", ErrUnsafeIdentifier, name)
+	}
+
+	for i, part := range parts {
+		if !identifierPattern.MatchString(part) || len(part) > maxIdentifierLength {
+			return "", fmt.Errorf("%w: %q", ErrUnsafeIdentifier, name)
+		}
+		parts[i] = `"` + strings.ToLower(part) + `"`
+	}
+
+	return strings.Join(parts, "."), nil
+}
+
+// quoteIdents quotes every name with quoteIdent
+func quoteIdents(names []string) ([]string, error) {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		q, err := quoteIdent(name)
+		if err != nil {
+			return nil, err
+		}
+		quoted[i] = q
+	}
+
+	return quoted, nil
+}
+
+// AllowAllRows lets Update and Delete run without conditions, affecting every row of the table
+func (qb *QueryBuilderImpl) AllowAllRows() QueryBuilder {
+	q := qb.clone()
+	q.allowAllRows = true
+	return q
+}
+
+// withErr returns a copy of qb failing with err when it runs, builder methods cannot return errors
+func (qb *QueryBuilderImpl) withErr(err error) *QueryBuilderImpl {
+	q := qb.clone()
+	if q.err == nil {
+		q.err = err
+	}
+	return q
+}
+
+// checkWhere refuses to run an Update or Delete on every row unless AllowAllRows is set
+func (qb *QueryBuilderImpl) checkWhere() error {
+	if len(qb.conditions) == 0 && !qb.allowAllRows {
+		return ErrMissingWhere
+	}
+	return nil
+}
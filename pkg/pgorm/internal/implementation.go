@@ -1,39 +1,160 @@
 package internal
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"reflect"
 	"strings"
 )
 
+// NewQueryBuilder returns a handle on DB. The handle is never modified: every builder
+// method returns a new query, so it can be shared and used concurrently.
 func NewQueryBuilder(DB *sql.DB) *QueryBuilderImpl {
 	return &QueryBuilderImpl{db: DB}
 }
 
+// clone returns a copy of the query that can be modified without affecting qb
+func (qb *QueryBuilderImpl) clone() *QueryBuilderImpl {
+	q := *qb
+	q.columns = append([]string(nil), qb.columns...)
+	q.values = append([]interface{}(nil), qb.values...)
+	q.conditions = append([]condition(nil), qb.conditions...)
+	q.orderBy = append([]orderTerm(nil), qb.orderBy...)
+	q.selectColumns = append([]string(nil), qb.selectColumns...)
+	q.joins = append([]join(nil), qb.joins...)
+	q.groupBy = append([]string(nil), qb.groupBy...)
+	q.having = append([]condition(nil), qb.having...)
+	return &q
+}
+
+// WithContext returns the query running with ctx, so that its cancellation and deadline reach the database.
+// The context is kept by the queries started from the result with Table.
+func (qb *QueryBuilderImpl) WithContext(ctx context.Context) QueryBuilder {
+	q := qb.clone()
+	q.ctx = ctx
+	return q
+}
+
+// context returns the context of the query, context.Background() when none was set
+func (qb *QueryBuilderImpl) context() context.Context {
+	if qb.ctx == nil {
+		return context.Background()
+	}
+	return qb.ctx
+}
+
+// Table starts a new query on the table of model, nothing set on qb is carried over
 func (qb *QueryBuilderImpl) Table(model Model) QueryBuilder {
-	qb.tableName = model.TableName()
-	return qb
+	q := &QueryBuilderImpl{db: qb.db, depth: qb.depth, ctx: qb.ctx}
+
+	table, err := quoteIdent(model.TableName())
+	if err != nil {
+		return q.withErr(err)
+	}
+	q.tableName = table
+
+	t := reflect.TypeOf(model)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Struct {
+		for _, f := range modelFields(t) {
+			if f.softDelete {
+				if q.softDelete, err = quoteIdent(f.column); err != nil {
+					return q.withErr(err)
+				}
+			}
+		}
+	}
+
+	return q
+}
+
+// TableAs is Table with an alias to qualify the columns of the table, e.g. with joins
+// TableAs(User{}, "u").Join("orders o", "o.user_id = u.id")
+func (qb *QueryBuilderImpl) TableAs(model Model, alias string) QueryBuilder {
+	q := qb.Table(model).(*QueryBuilderImpl)
+
+	quoted, err := quoteIdent(alias)
+	if err != nil || strings.Contains(alias, ".") {
+		return q.withErr(fmt.Errorf("%w: %q", ErrUnsafeIdentifier, alias))
+	}
+	q.alias = quoted
+
+	return q
+}
+
+// target renders the table of the query followed by its alias
+func (qb *QueryBuilderImpl) target() string {
+	if qb.alias == "" {
+		return qb.tableName
+	}
+	return qb.tableName + " AS " + qb.alias
+}
+
+// qualifier returns the name qualifying the columns of the table
+func (qb *QueryBuilderImpl) qualifier() string {
+	if qb.alias == "" {
+		return qb.tableName
+	}
+	return qb.alias
 }
 
 func (qb *QueryBuilderImpl) Returning(model interface{}, columns ...string) QueryBuilder {
+	q := qb.clone()
+
 	if len(columns) == 0 {
-		qb.returning = "" // Explicitly set no RETURNING clause
-	} else if len(columns) == 1 && columns[0] == "*" {
-		columnVals, _, _ := extractColumnsAndValues(model)
-		qb.returning = strings.Join(columnVals, ", ") // Return all columns
-	} else {
-		qb.returning = strings.Join(columns, ", ") //Return sepcified columns
+		q.returning = "" // Explicitly set no RETURNING clause
+		return q
+	}
+
+	if len(columns) == 1 && columns[0] == "*" {
+		columns = columnNames(model) // Return all columns
 	}
 
-	return qb
+	quoted, err := quoteIdents(columns)
+	if err != nil {
+		return q.withErr(err)
+	}
+	q.returning = strings.Join(quoted, ", ")
+
+	return q
 }
 
 func (qb *QueryBuilderImpl) Insert(model interface{}) (Result, error) {
-	qb.operation = "INSERT"
+	s, err := qb.insertStatement(model)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result, err := qb.execute(s.query, s.args)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return result, afterInsert(s.models)
+}
+
+func (qb *QueryBuilderImpl) insertStatement(model interface{}) (statement, error) {
+	if qb.err != nil {
+		return statement{}, qb.err
+	}
+
+	model, err := beforeInsert(model)
+	if err != nil {
+		return statement{}, err
+	}
+
 	columns, values, placeholders := extractColumnsAndValues(model)
 
 	if len(columns) == 0 || len(values) == 0 {
-		return Result{}, fmt.Errorf("no valid fields to insert")
+		return statement{}, fmt.Errorf("no valid fields to insert")
+	}
+
+	columns, err = quoteIdents(columns)
+	if err != nil {
+		return statement{}, err
 	}
 
 	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
@@ -42,83 +163,80 @@ func (qb *QueryBuilderImpl) Insert(model interface{}) (Result, error) {
 		strings.Join(placeholders, ", "),
 	)
 
-	if qb.returning != "" {
-		query += fmt.Sprintf(" RETURNING %s", qb.returning)
-
-		rows, err := qb.db.Query(query, values...)
-		if err != nil {
-			return Result{}, err
-		}
-		defer rows.Close()
-
-		returningResults := map[string]interface{}{}
-		rowsAffected := 0
-		if rows.Next() {
-			columnNames := strings.Split(qb.returning, ", ")
-			columns := make([]interface{}, len(columnNames))
-			columnPointers := make([]interface{}, len(columnNames))
-			for i := range columns {
-				columnPointers[i] = &columns[i]
-			}
-
-			if err := rows.Scan(columnPointers...); err != nil {
-				return Result{}, fmt.Errorf("error scanning returning columns: %w", err)
-			}
-
-			for i, col := range columnNames {
-				returningResults[col] = columns[i]
-			}
-			rowsAffected++
-		}
+	return statement{query: query, args: values, models: []interface{}{model}}, nil
+}
 
-		return Result{RowsAffected: int64(rowsAffected), Returning: returningResults}, nil
+func (qb *QueryBuilderImpl) Set(model interface{}) QueryBuilder {
+	q := qb.clone()
 
+	model, err := beforeUpdate(model)
+	if err != nil {
+		return q.withErr(err)
 	}
 
-	result, err := qb.db.Exec(query, values...)
+	columns, values := modelColumns(model, forUpdate)
+	columns, err = quoteIdents(columns)
 	if err != nil {
-		return Result{}, err
+		return q.withErr(err)
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	return Result{RowsAffected: rowsAffected}, nil
-}
-
-func (qb *QueryBuilderImpl) Set(model interface{}) QueryBuilder {
-	columns, values, _ := extractColumnsAndValues(model)
 	setClauses := []string{}
 	for i, column := range columns {
 		setClauses = append(setClauses, fmt.Sprintf("%s = $%d", column, i+1))
 	}
-	qb.columns = setClauses
-	qb.values = values
-	return qb
+	q.columns = setClauses
+	q.values = values
+	return q
 }
 
 func (qb *QueryBuilderImpl) Update() (Result, error) {
-	qb.operation = "UPDATE"
+	s, err := qb.updateStatement()
+	if err != nil {
+		return Result{}, err
+	}
+
+	return qb.execute(s.query, s.args)
+}
+
+func (qb *QueryBuilderImpl) updateStatement() (statement, error) {
+	if qb.err != nil {
+		return statement{}, qb.err
+	}
+
+	if err := qb.checkWhere(); err != nil {
+		return statement{}, err
+	}
+
+	where, whereArgs := qb.whereClause(len(qb.values))
 	query := fmt.Sprintf("UPDATE %s SET %s %s",
-		qb.tableName,
+		qb.target(),
 		strings.Join(qb.columns, ", "),
-		qb.where,
+		where,
 	)
 
-	args := append(qb.values, qb.whereArgs...)
+	args := append(append([]interface{}{}, qb.values...), whereArgs...)
 
+	return statement{query: query, args: args}, nil
+}
+
+// execute runs query, reading every row of the RETURNING clause when one is set
+func (qb *QueryBuilderImpl) execute(query string, args []interface{}) (Result, error) {
 	if qb.returning != "" {
 		query += fmt.Sprintf(" RETURNING %s", qb.returning)
 
-		rows, err := qb.db.Query(query, args...)
-
+		rows, err := qb.db.QueryContext(qb.context(), query, args...)
 		if err != nil {
 			return Result{}, err
 		}
 		defer rows.Close()
 
-		returningResults := map[string]interface{}{}
-		rowsAffected := 0
-		if rows.Next() {
-			columnNames := strings.Split(qb.returning, ", ")
+		columnNames, err := rows.Columns()
+		if err != nil {
+			return Result{}, fmt.Errorf("error fetching columns: %w", err)
+		}
+
+		result := Result{Rows: []map[string]interface{}{}}
+		for rows.Next() {
 			columns := make([]interface{}, len(columnNames))
 			columnPointers := make([]interface{}, len(columnNames))
 			for i := range columns {
@@ -129,16 +247,28 @@ func (qb *QueryBuilderImpl) Update() (Result, error) {
 				return Result{}, fmt.Errorf("error scanning returning columns: %w", err)
 			}
 
+			row := map[string]interface{}{}
 			for i, col := range columnNames {
-				returningResults[col] = columns[i]
+				row[col] = columns[i]
 			}
-			rowsAffected++
+			result.Rows = append(result.Rows, row)
+		}
+
+		if err := rows.Err(); err != nil {
+			return Result{}, fmt.Errorf("row iteration error: %w", err)
 		}
 
-		return Result{RowsAffected: int64(rowsAffected), Returning: returningResults}, nil
+		// every affected row is returned
+		result.RowsAffected = int64(len(result.Rows))
+		result.Returning = map[string]interface{}{}
+		if len(result.Rows) > 0 {
+			result.Returning = result.Rows[0]
+		}
+
+		return result, nil
 	}
 
-	result, err := qb.db.Exec(query, args...)
+	result, err := qb.db.ExecContext(qb.context(), query, args...)
 	if err != nil {
 		return Result{}, err
 	}
@@ -148,21 +278,9 @@ func (qb *QueryBuilderImpl) Update() (Result, error) {
 }
 
 func (qb *QueryBuilderImpl) Select() (interface{}, error) {
-	qb.operation = "SELECT"
-
-	if len(qb.columns) == 0 {
-		qb.columns = append(qb.columns, "*")
-	}
-
-	query := fmt.Sprintf("SELECT %s FROM %s %s",
-		strings.Join(qb.columns, ", "),
-		qb.tableName,
-		qb.where,
-	)
-
-	rows, err := qb.db.Query(query, qb.whereArgs...)
+	rows, err := qb.Rows()
 	if err != nil {
-		return nil, fmt.Errorf("query execution error: %w", err)
+		return nil, err
 	}
 	defer rows.Close()
 
@@ -198,28 +316,58 @@ func (qb *QueryBuilderImpl) Select() (interface{}, error) {
 	return results, nil
 }
 
-func (qb *QueryBuilderImpl) Where(condition string, args ...interface{}) QueryBuilder {
-	placeholderIndex := len(qb.whereArgs) + len(qb.values)
-	qb.where = "WHERE " + replacePlaceholders(condition, placeholderIndex)
-	qb.whereArgs = append(qb.whereArgs, args...)
-	return qb
+// Rows runs the select and returns the raw result set, the caller must close it.
+// SelectInto, First and Find scan it into structs.
+func (qb *QueryBuilderImpl) Rows() (*sql.Rows, error) {
+	if qb.err != nil {
+		return nil, qb.err
+	}
+
+	s := qb.selectStatement()
+
+	rows, err := qb.db.QueryContext(qb.context(), s.query, s.args...)
+	if err != nil {
+		return nil, fmt.Errorf("query execution error: %w", err)
+	}
+
+	return rows, nil
 }
 
 func (qb *QueryBuilderImpl) Delete() (Result, error) {
-	qb.operation = "DELETE"
+	s, err := qb.deleteStatement()
+	if err != nil {
+		return Result{}, err
+	}
+
+	result, err := qb.execute(s.query, s.args)
+	if err != nil {
+		return Result{}, fmt.Errorf("delete operation failed: %w", err)
+	}
+
+	return result, nil
+}
+
+func (qb *QueryBuilderImpl) deleteStatement() (statement, error) {
+	if qb.err != nil {
+		return statement{}, qb.err
+	}
 
 	if qb.tableName == "" {
-		return Result{}, fmt.Errorf("table name is not specified")
+		return statement{}, fmt.Errorf("table name is not specified")
 	}
 
-	query := fmt.Sprintf("DELETE FROM %s %s", qb.tableName, qb.where)
+	if err := qb.checkWhere(); err != nil {
+		return statement{}, err
+	}
 
-	// Execute the query with the `where` arguments
-	result, err := qb.db.Exec(query, qb.whereArgs...)
-	if err != nil {
-		return Result{}, fmt.Errorf("delete operation failed: %w", err)
+	if qb.softDelete != "" && !qb.unscoped {
+		where, whereArgs := qb.whereClause(1)
+		query := fmt.Sprintf("UPDATE %s SET %s = $1 %s", qb.target(), qb.softDelete, where)
+		return statement{query: query, args: append([]interface{}{now()}, whereArgs...)}, nil
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	return Result{RowsAffected: rowsAffected}, nil
+	where, whereArgs := qb.whereClause(0)
+	query := fmt.Sprintf("DELETE FROM %s %s", qb.target(), where)
+
+	return statement{query: query, args: whereArgs}, nil
 }
@@ -1,11 +1,15 @@
 package internal
 
 import (
+	"context"
+	"database/sql"
 	"database/sql/driver"
+	"errors"
 	"fmt"
 	"reflect"
 	"regexp"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -60,7 +64,7 @@ func TestInsert(t *testing.T) {
 	}
 	defer db.Close()
 
-	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO mock_table (id, email) VALUES ($1, $2)`)).
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "mock_table" ("id", "email") VALUES ($1, $2)`)).
 		WithArgs(1, "test@example.com").
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
@@ -91,12 +95,12 @@ func TestInsertWithReturning(t *testing.T) {
 	model := MockModel{ID: 1, Name: "john", Email: "test@example.com"}
 
 	// Columns and values for the query
-	columns := []string{"id", "name", "email"}
+	columns := []string{`"id"`, `"name"`, `"email"`}
 	values := []interface{}{1, "john", "test@example.com"}
 	placeholders := []string{"$1", "$2", "$3"}
 
 	// Expected query with RETURNING clause
-	query := fmt.Sprintf("INSERT INTO mock_table (%s) VALUES (%s) RETURNING id, name, email",
+	query := fmt.Sprintf(`INSERT INTO "mock_table" (%s) VALUES (%s) RETURNING "id", "name", "email"`,
 		strings.Join(columns, ", "),
 		strings.Join(placeholders, ", "),
 	)
@@ -153,15 +157,14 @@ func TestUpdate(t *testing.T) {
 	}
 	defer db.Close()
 
-	mock.ExpectExec(regexp.QuoteMeta(`UPDATE mock_table SET id = $1 WHERE id = $2`)).
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "mock_table" SET "id" = $1 WHERE id = $2`)).
 		WithArgs(2, 1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	queryBuilder := NewQueryBuilder(db)
 	model := MockModel{ID: 2}
 
-	queryBuilder.Table(model).Set(model).Where("id = ?", 1)
-	result, err := queryBuilder.Update()
+	result, err := queryBuilder.Table(model).Set(model).Where("id = ?", 1).Update()
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -183,7 +186,8 @@ func TestUpdateWithReturning(t *testing.T) {
 	defer db.Close()
 
 	// Define the expected SQL query for returning columns
-	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE mock_table SET id = $1, name = $2, email = $3 WHERE id = $1 RETURNING id, name, email`)).
+	// WHERE placeholders are numbered after the SET values even when Where is called first
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "mock_table" SET "id" = $1, "name" = $2, "email" = $3 WHERE id = $4 RETURNING "id", "name", "email"`)).
 		WithArgs(1, "john", "test@example.com", 1).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email"}).
 			AddRow(1, "john", "test@example.com")) // Simulating the returned columns
@@ -231,15 +235,14 @@ func TestDelete(t *testing.T) {
 	}
 	defer db.Close()
 
-	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM mock_table WHERE id = $1`)).
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "mock_table" WHERE id = $1`)).
 		WithArgs(1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	queryBuilder := NewQueryBuilder(db)
 	model := MockModel{}
 
-	queryBuilder.Table(model).Where("id = ?", 1)
-	result, err := queryBuilder.Delete()
+	result, err := queryBuilder.Table(model).Where("id = ?", 1).Delete()
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -263,15 +266,14 @@ func TestSelect(t *testing.T) {
 	row := sqlmock.NewRows([]string{"id", "name", "email"}).
 		AddRow(1, "John Doe", "john.doe@example.com")
 
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM mock_table WHERE id = $1`)).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "mock_table" WHERE id = $1`)).
 		WithArgs(1).
 		WillReturnRows(row)
 
 	queryBuilder := NewQueryBuilder(db)
 	model := MockModel{}
 
-	queryBuilder.Table(model).Where("id = ?", 1)
-	result, err := queryBuilder.Select()
+	result, err := queryBuilder.Table(model).Where("id = ?", 1).Select()
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -349,13 +351,13 @@ func TestReturning(t *testing.T) {
 			name:     "Return all columns with *",
 			columns:  []string{"*"},
 			model:    MockModel{ID: 1, Name: "John", Email: "john@example.com"},
-			expected: "id, name, email",
+			expected: `"id", "name", "email"`,
 		},
 		{
 			name:     "Return specific columns",
 			columns:  []string{"id", "name"},
 			model:    MockModel{ID: 1, Name: "John", Email: "john@example.com"},
-			expected: "id, name",
+			expected: `"id", "name"`,
 		},
 	}
 
@@ -378,3 +380,1381 @@ func TestReturning(t *testing.T) {
 		})
 	}
 }
+
+type MockProfile struct {
+	ID        int               `db:"id"`
+	Name      string            `db:"name"`
+	Nickname  sql.NullString    `db:"nickname"`
+	Phone     *string           `db:"phone"`
+	CreatedAt time.Time         `db:"created_at"`
+	Settings  map[string]string `db:"settings"`
+	Ignored   string
+}
+
+func (m MockProfile) TableName() string {
+	return "profiles"
+}
+
+func TestSelectInto(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	createdAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"id", "name", "nickname", "phone", "created_at", "settings", "extra"}).
+		AddRow(1, "John", "Johnny", "555-0100", createdAt, []byte(`{"theme":"dark"}`), "x").
+		AddRow(2, "Jane", nil, nil, createdAt, nil, "y")
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "profiles" WHERE id > $1`)).
+		WithArgs(0).
+		WillReturnRows(rows)
+
+	profiles, err := SelectInto[MockProfile](NewQueryBuilder(db).Table(MockProfile{}).Where("id > ?", 0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	phone := "555-0100"
+	expected := []MockProfile{
+		{ID: 1, Name: "John", Nickname: sql.NullString{String: "Johnny", Valid: true}, Phone: &phone, CreatedAt: createdAt, Settings: map[string]string{"theme": "dark"}},
+		{ID: 2, Name: "Jane", CreatedAt: createdAt},
+	}
+
+	if !reflect.DeepEqual(profiles, expected) {
+		t.Errorf("expected %+v, got %+v", expected, profiles)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("mock expectations were not met: %v", err)
+	}
+}
+
+func TestFirst(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "mock_table" WHERE id = $1`)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email"}).AddRow(1, "John", "john@example.com"))
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "mock_table" WHERE id = $1`)).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email"}))
+
+	model, err := First[MockModel](NewQueryBuilder(db).Table(MockModel{}).Where("id = ?", 1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if model != (MockModel{ID: 1, Name: "John", Email: "john@example.com"}) {
+		t.Errorf("unexpected model %+v", model)
+	}
+
+	_, err = First[MockModel](NewQueryBuilder(db).Table(MockModel{}).Where("id = ?", 2))
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("mock expectations were not met: %v", err)
+	}
+}
+
+func TestFind(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "mock_table" WHERE email = $1`)).
+		WithArgs("john@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(1, "john@example.com"))
+
+	models, err := Find[MockModel](NewQueryBuilder(db), "email = ?", "john@example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(models, []MockModel{{ID: 1, Email: "john@example.com"}}) {
+		t.Errorf("unexpected models %+v", models)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("mock expectations were not met: %v", err)
+	}
+}
+
+func TestQueryBuilderIsNotShared(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "mock_table" WHERE id = $1`)).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "mock_table"`)).
+		WithArgs().
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "mock_table" WHERE email = $1`)).
+		WithArgs("john@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	qb := NewQueryBuilder(db)
+
+	if _, err := qb.Table(MockModel{}).Where("id = ?", 1).Delete(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the WHERE of the delete must not leak into the next query on qb
+	if _, err := qb.Table(MockModel{}).Select(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// a partially built query can be reused as a base
+	base := qb.Table(MockModel{})
+	base.Where("id = ?", 2)
+	if _, err := base.Where("email = ?", "john@example.com").Select(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("mock expectations were not met: %v", err)
+	}
+}
+
+func TestQueryBuilderConcurrentUse(t *testing.T) {
+	base := NewQueryBuilder(nil).Table(MockModel{}).Where("name = ?", "john")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			q := base.Set(MockModel{ID: i}).(*QueryBuilderImpl)
+			if len(q.values) != 1 || q.values[0] != i || q.conditions[0].args[0] != "john" {
+				t.Errorf("unexpected query state %+v", q)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if impl := base.(*QueryBuilderImpl); len(impl.values) != 0 {
+		t.Errorf("expected base query to be unchanged, got %+v", impl)
+	}
+}
+
+func TestWhereComposition(t *testing.T) {
+	qb := NewQueryBuilder(nil).Table(MockModel{}).
+		Where("name = ?", "john").
+		WhereIn("id", []int{1, 2, 3}).
+		OrWhereGroup(func(g QueryBuilder) QueryBuilder {
+			return g.Where("email = ?", "a@example.com").OrWhere("email IN (?)", []string{"b@example.com", "c@example.com"})
+		}).
+		WhereNull("deleted_at").
+		WhereEq(MockModel{Email: "d@example.com"})
+
+	where, args := qb.(*QueryBuilderImpl).whereClause(2)
+
+	expectedWhere := `WHERE (name = $3) AND ("id" IN ($4, $5, $6)) OR ((email = $7) OR (email IN ($8, $9))) AND ("deleted_at" IS NULL) AND ("email" = $10)`
+	if where != expectedWhere {
+		t.Errorf("expected %s, got %s", expectedWhere, where)
+	}
+
+	expectedArgs := []interface{}{"john", 1, 2, 3, "a@example.com", "b@example.com", "c@example.com", "d@example.com"}
+	if !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("expected args %v, got %v", expectedArgs, args)
+	}
+}
+
+func TestWhereInEmptyAndBytes(t *testing.T) {
+	qb := NewQueryBuilder(nil).Table(MockModel{}).
+		WhereIn("id", []int{}).
+		Where("hash = ?", []byte("abc"))
+
+	where, args := qb.(*QueryBuilderImpl).whereClause(0)
+
+	if where != `WHERE ("id" IN (NULL)) AND (hash = $1)` {
+		t.Errorf("unexpected where %s", where)
+	}
+	if !reflect.DeepEqual(args, []interface{}{[]byte("abc")}) {
+		t.Errorf("unexpected args %v", args)
+	}
+}
+
+func TestUpdateWithChainedWhere(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "mock_table" SET "id" = $1, "email" = $2 WHERE (name = $3) AND ("id" IN ($4, $5))`)).
+		WithArgs(3, "new@example.com", "john", 1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	result, err := NewQueryBuilder(db).Table(MockModel{}).
+		Where("name = ?", "john").
+		WhereIn("id", []int{1, 2}).
+		Set(MockModel{ID: 3, Email: "new@example.com"}).
+		Update()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.RowsAffected != 2 {
+		t.Errorf("expected 2 rows affected, got %d", result.RowsAffected)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("mock expectations were not met: %v", err)
+	}
+}
+
+func TestSelectOrderLimitOffset(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "mock_table" WHERE name = $1 ORDER BY "name" DESC, "id" LIMIT 10 OFFSET 20`)).
+		WithArgs("john").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err = NewQueryBuilder(db).Table(MockModel{}).Where("name = ?", "john").OrderByDesc("name").OrderBy("id").Limit(10).Offset(20).Select()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("mock expectations were not met: %v", err)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM "mock_table" WHERE name = $1`)).
+		WithArgs("john").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "mock_table" WHERE name = $1 ORDER BY "id" LIMIT 2 OFFSET 2`)).
+		WithArgs("john").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(3, "john").AddRow(4, "john"))
+
+	page, err := Paginate[MockModel](NewQueryBuilder(db).Table(MockModel{}).Where("name = ?", "john").OrderBy("id"), 2, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if page.Total != 5 || page.TotalPages != 3 || page.Page != 2 || len(page.Items) != 2 || page.Items[0].ID != 3 {
+		t.Errorf("unexpected page %+v", page)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("mock expectations were not met: %v", err)
+	}
+}
+
+func TestKeysetPage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "mock_table" ORDER BY "name" DESC, "id" LIMIT 3`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "zoe").AddRow(7, "john").AddRow(9, "john"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "mock_table" WHERE (("name" < $1) OR ("name" = $2 AND "id" > $3)) ORDER BY "name" DESC, "id" LIMIT 3`)).
+		WithArgs("john", "john", "7").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(9, "john"))
+
+	qb := NewQueryBuilder(db).Table(MockModel{}).OrderByDesc("name").OrderBy("id")
+
+	items, cursor, err := KeysetPage[MockModel](qb, 2, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 2 || items[1].ID != 7 || cursor == "" {
+		t.Fatalf("unexpected first page %+v, cursor %q", items, cursor)
+	}
+
+	items, cursor, err = KeysetPage[MockModel](qb, 2, cursor)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 1 || items[0].ID != 9 || cursor != "" {
+		t.Errorf("unexpected last page %+v, cursor %q", items, cursor)
+	}
+
+	if _, _, err := KeysetPage[MockModel](qb, 2, "not-a-cursor"); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("mock expectations were not met: %v", err)
+	}
+}
+
+func TestTransactionCommit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "mock_table" ("id", "name") VALUES ($1, $2)`)).
+		WithArgs(1, "john").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "mock_table" WHERE id = $1`)).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = NewQueryBuilder(db).Transaction(context.Background(), func(tx QueryBuilder) error {
+		if _, err := tx.Table(MockModel{}).Insert(MockModel{ID: 1, Name: "john"}); err != nil {
+			return err
+		}
+		_, err := tx.Table(MockModel{}).Where("id = ?", 2).Delete()
+		return err
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("mock expectations were not met: %v", err)
+	}
+}
+
+func TestTransactionRollback(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	qb := NewQueryBuilder(db)
+	failure := errors.New("failure")
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	err = qb.Transaction(context.Background(), func(tx QueryBuilder) error {
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("expected the error of fn, got %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("expected the panic to be propagated, got %v", p)
+			}
+		}()
+		qb.Transaction(context.Background(), func(tx QueryBuilder) error {
+			panic("boom")
+		})
+	}()
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("mock expectations were not met: %v", err)
+	}
+}
+
+func TestTransactionSavepoints(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	failure := errors.New("failure")
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SAVEPOINT pgorm_savepoint_1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT pgorm_savepoint_1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SAVEPOINT pgorm_savepoint_1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SAVEPOINT pgorm_savepoint_2").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("RELEASE SAVEPOINT pgorm_savepoint_2").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("RELEASE SAVEPOINT pgorm_savepoint_1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	ctx := context.Background()
+	err = NewQueryBuilder(db).Transaction(ctx, func(tx QueryBuilder) error {
+		// the failed savepoint does not abort the transaction
+		if err := tx.Transaction(ctx, func(QueryBuilder) error { return failure }); !errors.Is(err, failure) {
+			t.Errorf("expected the error of the savepoint, got %v", err)
+		}
+
+		return tx.Table(MockModel{}).Transaction(ctx, func(nested QueryBuilder) error {
+			return nested.Transaction(ctx, func(QueryBuilder) error { return nil })
+		})
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("mock expectations were not met: %v", err)
+	}
+}
+
+func TestTransactionWithOptions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	opts := &sql.TxOptions{Isolation: sql.LevelSerializable}
+	if err := NewQueryBuilder(db).TransactionWithOptions(context.Background(), opts, func(QueryBuilder) error { return nil }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("mock expectations were not met: %v", err)
+	}
+}
+
+func TestQueryBuilderWithExecutor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "mock_table" WHERE id = $1`)).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := NewQueryBuilderWithExecutor(tx).Table(MockModel{}).Where("id = ?", 1).Delete(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("mock expectations were not met: %v", err)
+	}
+}
+
+func TestWithContext(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "mock_table" WHERE id = $1`)).
+		WithArgs(1).
+		WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// the context is kept by the queries started with Table
+	qb := NewQueryBuilder(db).WithContext(ctx)
+	start := time.Now()
+	if _, err := qb.Table(MockModel{}).Where("id = ?", 1).Select(); err == nil || time.Since(start) >= time.Second {
+		t.Errorf("expected the deadline to cancel the query, got %v after %v", err, time.Since(start))
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := qb.WithContext(cancelled).Table(MockModel{}).Insert(MockModel{ID: 1}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a cancelled insert, got %v", err)
+	}
+}
+
+func TestInsertMany(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "mock_table" ("id", "name") VALUES ($1, $2), ($3, $4)`)).
+		WithArgs(1, "john", 2, "jane").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	result, err := NewQueryBuilder(db).Table(MockModel{}).InsertMany([]MockModel{{ID: 1, Name: "john"}, {ID: 2, Name: "jane"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.RowsAffected != 2 {
+		t.Errorf("expected 2 rows affected, got %d", result.RowsAffected)
+	}
+
+	// models with different columns are inserted by separate statements in a transaction
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "mock_table" ("id", "name") VALUES ($1, $2)`)).
+		WithArgs(1, "john").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "mock_table" ("id", "email") VALUES ($1, $2)`)).
+		WithArgs(2, "jane@email.com").
+		WillReturnError(errors.New("duplicate key"))
+	mock.ExpectRollback()
+
+	_, err = NewQueryBuilder(db).Table(MockModel{}).InsertMany([]*MockModel{{ID: 1, Name: "john"}, {ID: 2, Email: "jane@email.com"}})
+	if err == nil {
+		t.Error("expected the insert to fail")
+	}
+
+	if _, err := NewQueryBuilder(db).Table(MockModel{}).InsertMany(MockModel{}); err == nil {
+		t.Error("expected an error inserting a struct instead of a slice")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("mock expectations were not met: %v", err)
+	}
+}
+
+func TestInsertStatementsChunking(t *testing.T) {
+	models := make([]MockModel, maxParams/2+2)
+	for i := range models {
+		models[i] = MockModel{ID: i, Name: "user"}
+	}
+
+	statements, err := (&QueryBuilderImpl{tableName: "mock_table"}).insertStatements(models)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(statements) != 2 || len(statements[0].args) != maxParams-1 || len(statements[1].args) != 4 {
+		t.Errorf("unexpected chunks of %d statements", len(statements))
+	}
+	if !strings.HasSuffix(statements[1].query, "VALUES ($1, $2), ($3, $4)") {
+		t.Errorf("expected placeholders to restart in every statement, got %s", statements[1].query)
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	qb := NewQueryBuilder(db).Table(MockModel{})
+
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "mock_table" ("id", "name") VALUES ($1, $2) ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name"`)).
+		WithArgs(1, "john").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := qb.Upsert(MockModel{ID: 1, Name: "john"}, []string{"id"}, "name"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "mock_table" ("id", "name") VALUES ($1, $2) ON CONFLICT DO NOTHING`)).
+		WithArgs(1, "john").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	if _, err := qb.Upsert(MockModel{ID: 1, Name: "john"}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := qb.Upsert(MockModel{ID: 1, Name: "john"}, nil, "name"); err == nil {
+		t.Error("expected an error updating without conflict columns")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("mock expectations were not met: %v", err)
+	}
+}
+
+func TestUpsertInto(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "mock_table" ("id", "email") VALUES ($1, $2) ON CONFLICT ("id") DO UPDATE SET "email" = EXCLUDED."email" RETURNING *`)).
+		WithArgs(1, "john@email.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email"}).AddRow(1, "john", "john@email.com"))
+
+	user, err := UpsertInto(NewQueryBuilder(db), MockModel{ID: 1, Email: "john@email.com"}, []string{"id"}, "email")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if user.Name != "john" || user.Email != "john@email.com" {
+		t.Errorf("unexpected row %+v", user)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "mock_table" ("id") VALUES ($1) ON CONFLICT ("id") DO NOTHING RETURNING *`)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email"}))
+
+	if _, err := UpsertInto(NewQueryBuilder(db), MockModel{ID: 1}, []string{"id"}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("mock expectations were not met: %v", err)
+	}
+}
+
+func TestUpdateReturningAllRows(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "mock_table" SET "id" = $1, "name" = $2 WHERE email = $3 RETURNING "id", "name"`)).
+		WithArgs(0, "john", "test@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "john").AddRow(2, "john").AddRow(3, "john"))
+
+	result, err := NewQueryBuilder(db).Table(MockModel{}).Set(MockModel{Name: "john"}).
+		Where("email = ?", "test@example.com").Returning(MockModel{}, "id", "name").Update()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.RowsAffected != 3 || len(result.Rows) != 3 || result.Rows[2]["id"].(int64) != 3 {
+		t.Errorf("expected the 3 updated rows, got %+v", result)
+	}
+	if result.Returning["id"].(int64) != 1 {
+		t.Errorf("expected Returning to hold the first row, got %v", result.Returning)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("mock expectations were not met: %v", err)
+	}
+}
+
+func TestReturningIntoModels(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	qb := NewQueryBuilder(db).Table(MockModel{})
+
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "mock_table" ("id", "name") VALUES ($1, $2) RETURNING *`)).
+		WithArgs(1, "john").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email"}).AddRow(1, "john", "default@example.com"))
+
+	inserted, err := InsertReturning[MockModel](qb, MockModel{ID: 1, Name: "john"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(inserted) != 1 || inserted[0].Email != "default@example.com" {
+		t.Errorf("unexpected inserted rows %+v", inserted)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "mock_table" SET "id" = $1, "name" = $2 WHERE "id" IN ($3, $4) RETURNING "id", "name"`)).
+		WithArgs(0, "jane", 1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "jane").AddRow(2, "jane"))
+
+	updated, err := UpdateReturning[MockModel](qb.Set(MockModel{Name: "jane"}).WhereIn("id", []int{1, 2}).Returning(MockModel{}, "id", "name"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(updated) != 2 || updated[1].ID != 2 || updated[1].Name != "jane" {
+		t.Errorf("unexpected updated rows %+v", updated)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`DELETE FROM "mock_table" WHERE name = $1 RETURNING *`)).
+		WithArgs("jane").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email"}).AddRow(1, "jane", "").AddRow(2, "jane", ""))
+
+	deleted, err := DeleteReturning[MockModel](qb.Where("name = ?", "jane"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(deleted) != 2 {
+		t.Errorf("expected 2 deleted rows, got %+v", deleted)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("mock expectations were not met: %v", err)
+	}
+}
+
+func TestQuoteIdent(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"user", `"user"`},
+		{"public.user", `"public"."user"`},
+		{"created_at", `"created_at"`},
+		{"createdAt", `"createdat"`},
+		{"Billing.Users", `"billing"."users"`},
+		{"id; DROP TABLE user", ""},
+		{"name DESC", ""},
+		{`na"me`, ""},
+		{"a.b.c.d", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		quoted, err := quoteIdent(tt.name)
+		if tt.expected == "" {
+			if !errors.Is(err, ErrUnsafeIdentifier) {
+				t.Errorf("expected %q to be rejected, got %q", tt.name, quoted)
+			}
+			continue
+		}
+
+		if err != nil || quoted != tt.expected {
+			t.Errorf("expected %q to be quoted as %s, got %s (%v)", tt.name, tt.expected, quoted, err)
+		}
+	}
+}
+
+type MockCamel struct {
+	ID       int    `db:"id"`
+	FullName string `db:"fullName"`
+}
+
+func (MockCamel) TableName() string {
+	return "People"
+}
+
+func TestFoldedIdentifiers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// the returned column is folded like the quoted one, and still scanned into the field
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "fullname" FROM "people" WHERE "fullname" IS NOT NULL`)).
+		WillReturnRows(sqlmock.NewRows([]string{"fullname"}).AddRow("John Doe"))
+
+	people, err := SelectInto[MockCamel](NewQueryBuilder(db).Table(MockCamel{}).Columns("fullName").WhereNotNull("fullName"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(people) != 1 || people[0].FullName != "John Doe" {
+		t.Errorf("unexpected people %+v", people)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("mock expectations were not met: %v", err)
+	}
+}
+
+func TestUnsafeIdentifiersRejected(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	qb := NewQueryBuilder(db).Table(MockModel{})
+
+	if _, err := qb.Returning(MockModel{}, "id; DROP TABLE mock_table").Insert(MockModel{ID: 1}); !errors.Is(err, ErrUnsafeIdentifier) {
+		t.Errorf("expected the returning column to be rejected, got %v", err)
+	}
+
+	if _, err := SelectInto[MockModel](qb.OrderBy("id DESC; --")); !errors.Is(err, ErrUnsafeIdentifier) {
+		t.Errorf("expected the order column to be rejected, got %v", err)
+	}
+
+	if _, err := qb.WhereGroup(func(g QueryBuilder) QueryBuilder { return g.WhereNull("1=1 OR id") }).Count(); !errors.Is(err, ErrUnsafeIdentifier) {
+		t.Errorf("expected the group column to be rejected, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("mock expectations were not met: %v", err)
+	}
+}
+
+func TestUpdateDeleteRequireWhere(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	qb := NewQueryBuilder(db).Table(MockModel{})
+
+	if _, err := qb.Set(MockModel{ID: 1}).Update(); !errors.Is(err, ErrMissingWhere) {
+		t.Errorf("expected the update to be refused, got %v", err)
+	}
+	if _, err := qb.Delete(); !errors.Is(err, ErrMissingWhere) {
+		t.Errorf("expected the delete to be refused, got %v", err)
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "mock_table"`)).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	result, err := qb.AllowAllRows().Delete()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.RowsAffected != 3 {
+		t.Errorf("expected 3 rows affected, got %d", result.RowsAffected)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("mock expectations were not met: %v", err)
+	}
+}
+
+type MockSchemaModel struct {
+	ID int `db:"id"`
+}
+
+func (MockSchemaModel) TableName() string {
+	return "billing.invoice"
+}
+
+func TestSchemaQualifiedTable(t *testing.T) {
+	qb := NewQueryBuilder(nil).Table(MockSchemaModel{}).(*QueryBuilderImpl)
+	if qb.err != nil || qb.tableName != `"billing"."invoice"` {
+		t.Errorf("unexpected table %s (%v)", qb.tableName, qb.err)
+	}
+}
+
+type MockAudit struct {
+	CreatedAt time.Time `db:"created_at,default"`
+	UpdatedAt time.Time `db:"updated_at,default"`
+}
+
+type MockSettings struct {
+	Theme string `json:"theme"`
+}
+
+type MockAccount struct {
+	MockAudit
+	ID       int          `db:"id,pk"`
+	Name     string       `db:"name"`
+	Slug     string       `db:"slug,readonly"`
+	Settings MockSettings `db:"settings,json"`
+	Tags     []string     `db:"tags,json"`
+	Secret   string       `db:"-"`
+	internal string       `db:"internal"`
+}
+
+func (MockAccount) TableName() string {
+	return "accounts"
+}
+
+func TestModelFieldsTagOptions(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	account := MockAccount{
+		MockAudit: MockAudit{CreatedAt: created},
+		Name:      "acme",
+		Slug:      "acme-1",
+		Settings:  MockSettings{Theme: "dark"},
+		Secret:    "secret",
+		internal:  "internal",
+	}
+
+	// zero pk and default fields are left to the database, readonly and ignored fields are never written
+	columns, values, _ := extractColumnsAndValues(account)
+	if expected := []string{"created_at", "name", "settings", "tags"}; !reflect.DeepEqual(columns, expected) {
+		t.Errorf("expected insert columns %v, got %v", expected, columns)
+	}
+	if values[0] != created || values[1] != "acme" {
+		t.Errorf("unexpected values %v", values)
+	}
+
+	settings, err := values[2].(driver.Valuer).Value()
+	if err != nil || settings != `{"theme":"dark"}` {
+		t.Errorf("expected settings as json, got %v (%v)", settings, err)
+	}
+	if tags, _ := values[3].(driver.Valuer).Value(); tags != nil {
+		t.Errorf("expected nil tags to be NULL, got %v", tags)
+	}
+
+	account.ID = 7
+	columns, _ = modelColumns(&account, forUpdate)
+	if expected := []string{"created_at", "name", "settings", "tags"}; !reflect.DeepEqual(columns, expected) {
+		t.Errorf("expected update columns %v, got %v", expected, columns)
+	}
+
+	if names := columnNames(MockAccount{}); !reflect.DeepEqual(names, []string{"created_at", "updated_at", "id", "name", "slug", "settings", "tags"}) {
+		t.Errorf("unexpected columns %v", names)
+	}
+}
+
+type MockShadowing struct {
+	MockAudit
+	UpdatedAt string `db:"updated_at"`
+}
+
+func TestModelFieldsShadowing(t *testing.T) {
+	fields := fieldsByColumn(reflect.TypeOf(MockShadowing{}))
+	if f := fields["updated_at"]; !reflect.DeepEqual(f.index, []int{1}) {
+		t.Errorf("expected the outer field to shadow the embedded one, got %v", f.index)
+	}
+	if f := fields["created_at"]; !reflect.DeepEqual(f.index, []int{0, 0}) {
+		t.Errorf("expected the embedded field to be flattened, got %v", f.index)
+	}
+}
+
+// mockDecimal is a Valuer with unexported fields, like decimal.Decimal
+type mockDecimal struct {
+	value int64
+	exp   int32
+}
+
+func (d mockDecimal) Value() (driver.Value, error) {
+	return fmt.Sprintf("%de%d", d.value, d.exp), nil
+}
+
+type MockInvoice struct {
+	ID       int          `db:"id,pk"`
+	Amount   mockDecimal  `db:"amount"`
+	Discount mockDecimal  `db:"discount,omitempty"`
+	Tax      *mockDecimal `db:"tax,default"`
+}
+
+func (MockInvoice) TableName() string {
+	return "invoices"
+}
+
+func TestModelColumnsValuerWithUnexportedFields(t *testing.T) {
+	invoice := MockInvoice{Amount: mockDecimal{value: 1250, exp: -2}, Tax: &mockDecimal{value: 5}}
+
+	columns, values, _ := extractColumnsAndValues(invoice)
+	if expected := []string{"amount", "tax"}; !reflect.DeepEqual(columns, expected) {
+		t.Errorf("expected insert columns %v, got %v", expected, columns)
+	}
+	if values[0] != invoice.Amount {
+		t.Errorf("expected the decimal to be bound as is, got %v", values[0])
+	}
+
+	invoice.Discount = mockDecimal{value: 10}
+	columns, _ = modelColumns(invoice, forFilter)
+	if expected := []string{"amount", "discount", "tax"}; !reflect.DeepEqual(columns, expected) {
+		t.Errorf("expected filter columns %v, got %v", expected, columns)
+	}
+}
+
+func TestWherePK(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	account := MockAccount{ID: 7, Name: "acme"}
+
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "accounts" SET "name" = $1, "settings" = $2, "tags" = $3 WHERE "id" = $4`)).
+		WithArgs("acme", `{"theme":""}`, nil, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if _, err := NewQueryBuilder(db).Table(account).Set(account).WherePK(account).Update(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := NewQueryBuilder(db).Table(MockModel{}).WherePK(MockModel{ID: 1}).Delete(); err == nil {
+		t.Error("expected an error for a model without pk")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("mock expectations were not met: %v", err)
+	}
+}
+
+func TestSelectIntoEmbeddedAndJSON(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "accounts"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "slug", "settings", "tags", "created_at"}).
+			AddRow(1, "acme", "acme-1", []byte(`{"theme":"dark"}`), []byte(`["a","b"]`), created))
+
+	accounts, err := SelectInto[MockAccount](NewQueryBuilder(db).Table(MockAccount{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	a := accounts[0]
+	if a.Slug != "acme-1" || a.Settings.Theme != "dark" || !reflect.DeepEqual(a.Tags, []string{"a", "b"}) || !a.CreatedAt.Equal(created) {
+		t.Errorf("unexpected account %+v", a)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("mock expectations were not met: %v", err)
+	}
+}
+
+type MockUserTotal struct {
+	Name   string `db:"name"`
+	Orders int    `db:"orders"`
+	Total  int    `db:"total"`
+}
+
+func TestSelectJoinGroupByHaving(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "u"."name", COUNT(o.id) AS orders, SUM(o.total) AS total FROM "mock_table" AS "u" JOIN "orders" "o" ON o.user_id = u.id AND o.status = $1 LEFT JOIN "public"."refunds" "r" ON r.order_id = o.id WHERE r.id IS NULL AND u.name = $2 GROUP BY "u"."name" HAVING COUNT(o.id) > $3 ORDER BY "total" DESC LIMIT 10`)).
+		WithArgs("paid", "john", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "orders", "total"}).AddRow("john", 3, 120))
+
+	qb := NewQueryBuilder(db).TableAs(MockModel{}, "u").
+		Columns("u.name").
+		ColumnExpr("COUNT(o.id) AS orders").
+		ColumnExpr("SUM(o.total) AS total").
+		Join("orders o", "o.user_id = u.id AND o.status = ?", "paid").
+		LeftJoin("public.refunds AS r", "r.order_id = o.id").
+		Where("r.id IS NULL AND u.name = ?", "john").
+		GroupBy("u.name").
+		Having("COUNT(o.id) > ?", 2).
+		OrderByDesc("total").
+		Limit(10)
+
+	totals, err := SelectInto[MockUserTotal](qb)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(totals) != 1 || totals[0].Orders != 3 || totals[0].Total != 120 {
+		t.Errorf("unexpected totals %+v", totals)
+	}
+
+	if _, err := SelectInto[MockUserTotal](qb.Join("orders; DROP TABLE orders", "true")); !errors.Is(err, ErrUnsafeIdentifier) {
+		t.Errorf("expected the joined table to be rejected, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("mock expectations were not met: %v", err)
+	}
+}
+
+type MockUser struct {
+	ID        int        `db:"uid,pk"`
+	Name      string     `db:"name"`
+	DeletedAt *time.Time `db:"deleted_at,softdelete"`
+}
+
+func (MockUser) TableName() string {
+	return "user"
+}
+
+func TestTableAs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	fixed := mockNow(t)
+	qb := NewQueryBuilder(db).TableAs(MockUser{}, "u")
+
+	// user is a reserved word, the alias lets conditions and expressions refer to the table
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "u"."name", COUNT(o.id) AS orders FROM "user" AS "u" JOIN "orders" "o" ON o.user_id = u.uid WHERE "u"."deleted_at" IS NULL GROUP BY "u"."name"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "orders"}).AddRow("john", 3))
+	totals, err := SelectInto[MockUserTotal](qb.Columns("u.name").ColumnExpr("COUNT(o.id) AS orders").Join("orders o", "o.user_id = u.uid").GroupBy("u.name"))
+	if err != nil || len(totals) != 1 || totals[0].Orders != 3 {
+		t.Fatalf("unexpected totals %+v (%v)", totals, err)
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "user" AS "u" SET "deleted_at" = $1 WHERE (u.uid = $2) AND ("u"."deleted_at" IS NULL)`)).
+		WithArgs(fixed, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := qb.Where("u.uid = ?", 1).Delete(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, alias := range []string{"u; DROP TABLE orders", "public.u", ""} {
+		if _, err := NewQueryBuilder(db).TableAs(MockUser{}, alias).Count(); !errors.Is(err, ErrUnsafeIdentifier) {
+			t.Errorf("expected alias %q to be rejected, got %v", alias, err)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("mock expectations were not met: %v", err)
+	}
+}
+
+func TestSelectIgnoresSetColumns(t *testing.T) {
+	qb := NewQueryBuilder(nil).Table(MockModel{}).Set(MockModel{ID: 1}).Columns("id", "name AS full_name", "mock_table.*")
+
+	s := qb.(*QueryBuilderImpl).selectStatement()
+	if s.query != `SELECT "id", "name" AS "full_name", "mock_table".* FROM "mock_table"` {
+		t.Errorf("unexpected select %s", s.query)
+	}
+}
+
+func TestCountAndExists(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	qb := NewQueryBuilder(db).Table(MockModel{})
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM (SELECT 1 FROM "mock_table" WHERE id > $1 GROUP BY "name") AS groups`)).
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+
+	count, err := qb.Where("id > ?", 10).GroupBy("name").OrderBy("name").Count()
+	if err != nil || count != 4 {
+		t.Errorf("expected 4 groups, got %d (%v)", count, err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM "mock_table" WHERE email = $1)`)).
+		WithArgs("john@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	exists, err := qb.Where("email = ?", "john@example.com").Exists()
+	if err != nil || !exists {
+		t.Errorf("expected the row to exist, got %v (%v)", exists, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("mock expectations were not met: %v", err)
+	}
+}
+
+func TestRaw(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT name, COUNT(*) AS orders, 0 AS total FROM orders WHERE user_id IN ($1, $2) AND status = $3 GROUP BY name`)).
+		WithArgs(1, 2, "paid").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "orders", "total"}).AddRow("john", 2, 0).AddRow("jane", 1, 0))
+
+	totals, err := Raw[MockUserTotal](NewQueryBuilder(db),
+		"SELECT name, COUNT(*) AS orders, 0 AS total FROM orders WHERE user_id IN (?) AND status = ? GROUP BY name", []int{1, 2}, "paid")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(totals) != 2 || totals[1].Name != "jane" {
+		t.Errorf("unexpected totals %+v", totals)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("mock expectations were not met: %v", err)
+	}
+}
+
+var errInvalidName = errors.New("name is required")
+
+type MockHooked struct {
+	ID        int        `db:"id,pk"`
+	Name      string     `db:"name"`
+	Slug      string     `db:"slug,omitempty"`
+	CreatedAt time.Time  `db:"created_at,created"`
+	UpdatedAt time.Time  `db:"updated_at,updated"`
+	DeletedAt *time.Time `db:"deleted_at,softdelete"`
+
+	calls []string `db:"-"`
+}
+
+func (MockHooked) TableName() string {
+	return "hooked"
+}
+
+func (m *MockHooked) BeforeInsert() error {
+	m.Slug = strings.ToLower(m.Name)
+	m.calls = append(m.calls, "BeforeInsert")
+	return nil
+}
+
+func (m *MockHooked) AfterInsert() error {
+	m.calls = append(m.calls, "AfterInsert")
+	return nil
+}
+
+func (m *MockHooked) BeforeUpdate() error {
+	m.calls = append(m.calls, "BeforeUpdate")
+	return nil
+}
+
+func (m *MockHooked) AfterFind() error {
+	m.calls = append(m.calls, "AfterFind")
+	return nil
+}
+
+func (m *MockHooked) Validate() error {
+	if m.Name == "" {
+		return errInvalidName
+	}
+	return nil
+}
+
+func mockNow(t *testing.T) time.Time {
+	fixed := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	now = func() time.Time { return fixed }
+	t.Cleanup(func() { now = time.Now })
+	return fixed
+}
+
+func TestInsertHooksAndTimestamps(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	fixed := mockNow(t)
+
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "hooked" ("name", "slug", "created_at", "updated_at", "deleted_at") VALUES ($1, $2, $3, $4, $5)`)).
+		WithArgs("Acme", "acme", fixed, fixed, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	model := &MockHooked{Name: "Acme"}
+	if _, err := NewQueryBuilder(db).Table(model).Insert(model); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(model.calls, []string{"BeforeInsert", "AfterInsert"}) || !model.CreatedAt.Equal(fixed) {
+		t.Errorf("unexpected model after insert %+v", model)
+	}
+
+	if _, err := NewQueryBuilder(db).Table(model).Insert(MockHooked{}); !errors.Is(err, errInvalidName) {
+		t.Errorf("expected the validation error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("mock expectations were not met: %v", err)
+	}
+}
+
+func TestUpdateHooksAndTimestamps(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	fixed := mockNow(t)
+	created := fixed.Add(-time.Hour)
+
+	// created_at and deleted_at are kept, updated_at set to now and deleted rows are not updated
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "hooked" SET "name" = $1, "updated_at" = $2 WHERE (id = $3) AND ("hooked"."deleted_at" IS NULL)`)).
+		WithArgs("Acme", fixed, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	model := &MockHooked{Name: "Acme", CreatedAt: created}
+	if _, err := NewQueryBuilder(db).Table(model).Set(model).Where("id = ?", 1).Update(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(model.calls, []string{"BeforeUpdate"}) || !model.UpdatedAt.Equal(fixed) {
+		t.Errorf("unexpected model after update %+v", model)
+	}
+
+	// an unscoped update does not restore a deleted row
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "hooked" SET "name" = $1, "updated_at" = $2 WHERE id = $3`)).
+		WithArgs("Acme", fixed, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := NewQueryBuilder(db).Table(model).Unscoped().Set(model).Where("id = ?", 1).Update(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := NewQueryBuilder(db).Table(model).Set(MockHooked{}).Where("id = ?", 1).Update(); !errors.Is(err, errInvalidName) {
+		t.Errorf("expected the validation error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("mock expectations were not met: %v", err)
+	}
+}
+
+func TestUpsertSetsUpdatedTimestamps(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	fixed := mockNow(t)
+
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "hooked" ("id", "name", "slug", "created_at", "updated_at", "deleted_at") VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name", "updated_at" = EXCLUDED."updated_at"`)).
+		WithArgs(1, "Acme", sqlmock.AnyArg(), fixed, fixed, nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	updateColumns := []string{"name"}
+	if _, err := NewQueryBuilder(db).Table(MockHooked{}).Upsert(&MockHooked{ID: 1, Name: "Acme"}, []string{"id"}, updateColumns...); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(updateColumns, []string{"name"}) {
+		t.Errorf("expected the update columns of the caller to be left as is, got %v", updateColumns)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("mock expectations were not met: %v", err)
+	}
+}
+
+func TestSoftDelete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	fixed := mockNow(t)
+	qb := NewQueryBuilder(db).Table(MockHooked{})
+
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "hooked" SET "deleted_at" = $1 WHERE (id = $2) AND ("hooked"."deleted_at" IS NULL)`)).
+		WithArgs(fixed, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := qb.Where("id = ?", 1).Delete(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "hooked" WHERE ((name = $1) OR (name = $2)) AND ("hooked"."deleted_at" IS NULL)`)).
+		WithArgs("a", "b").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "a"))
+	items, err := SelectInto[MockHooked](qb.Where("name = ?", "a").OrWhere("name = ?", "b"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 1 || !reflect.DeepEqual(items[0].calls, []string{"AfterFind"}) {
+		t.Errorf("expected AfterFind to run on the rows, got %+v", items)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM "hooked"`) + "$").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	if count, err := qb.Unscoped().Count(); err != nil || count != 3 {
+		t.Errorf("expected 3 rows with the deleted ones, got %d (%v)", count, err)
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "hooked" WHERE id = $1`)).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := qb.Unscoped().Where("id = ?", 1).Delete(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("mock expectations were not met: %v", err)
+	}
+}
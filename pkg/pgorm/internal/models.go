@@ -1,27 +1,72 @@
 package internal
 
-import "database/sql"
+import (
+	"context"
+	"database/sql"
+)
 
 type QueryBuilder interface {
 	Table(model Model) QueryBuilder
+	TableAs(model Model, alias string) QueryBuilder
+	WithContext(ctx context.Context) QueryBuilder
 	Returning(model interface{}, columns ...string) QueryBuilder
 	Set(model interface{}) QueryBuilder
 	Where(condition string, args ...interface{}) QueryBuilder
+	AndWhere(condition string, args ...interface{}) QueryBuilder
+	OrWhere(condition string, args ...interface{}) QueryBuilder
+	WhereGroup(fn func(QueryBuilder) QueryBuilder) QueryBuilder
+	OrWhereGroup(fn func(QueryBuilder) QueryBuilder) QueryBuilder
+	WhereIn(column string, values interface{}) QueryBuilder
+	WhereNull(column string) QueryBuilder
+	WhereNotNull(column string) QueryBuilder
+	WhereEq(model interface{}) QueryBuilder
+	WherePK(model interface{}) QueryBuilder
+	AllowAllRows() QueryBuilder
+	Unscoped() QueryBuilder
 	Insert(model interface{}) (Result, error)
+	InsertMany(models interface{}) (Result, error)
+	Upsert(model interface{}, conflictColumns []string, updateColumns ...string) (Result, error)
 	Update() (Result, error)
 	Delete() (Result, error)
 	Select() (interface{}, error)
+	Rows() (*sql.Rows, error)
+	OrderBy(column string) QueryBuilder
+	OrderByDesc(column string) QueryBuilder
+	Limit(n int) QueryBuilder
+	Offset(n int) QueryBuilder
+	Count() (int64, error)
+	Exists() (bool, error)
+	Columns(columns ...string) QueryBuilder
+	ColumnExpr(expr string) QueryBuilder
+	Join(table, on string, args ...interface{}) QueryBuilder
+	LeftJoin(table, on string, args ...interface{}) QueryBuilder
+	GroupBy(columns ...string) QueryBuilder
+	Having(expr string, args ...interface{}) QueryBuilder
+	Transaction(ctx context.Context, fn func(tx QueryBuilder) error) error
+	TransactionWithOptions(ctx context.Context, opts *sql.TxOptions, fn func(tx QueryBuilder) error) error
 }
 
 type QueryBuilderImpl struct {
-	db        *sql.DB
-	tableName string
-	columns   []string
-	values    []interface{}
-	operation string
-	where     string
-	whereArgs []interface{}
-	returning string
+	db            Executor
+	depth         int // savepoint nesting inside a transaction
+	ctx           context.Context
+	err           error // first error of the builder methods, returned when the query runs
+	tableName     string
+	alias         string // alias of the table, quoted
+	selectColumns []string
+	joins         []join
+	columns       []string // SET clauses of an update
+	values        []interface{}
+	conditions    []condition
+	groupBy       []string
+	having        []condition
+	orderBy       []orderTerm
+	limit         int
+	offset        int
+	returning     string
+	allowAllRows  bool
+	softDelete    string // soft delete column of the table, quoted
+	unscoped      bool
 }
 
 type Model interface {
@@ -30,5 +75,6 @@ type Model interface {
 
 type Result struct {
 	RowsAffected int64
-	Returning    map[string]interface{}
+	Returning    map[string]interface{}   // first row of the RETURNING clause
+	Rows         []map[string]interface{} // every row of the RETURNING clause
 }
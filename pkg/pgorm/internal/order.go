@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// orderTerm is one column of the ORDER BY clause
+type orderTerm struct {
+	column string
+	desc   bool
+}
+
+// quoted returns the column quoted, it was validated by OrderBy
+func (t orderTerm) quoted() string {
+	column, _ := quoteIdent(t.column)
+	return column
+}
+
+// OrderBy sorts the selected rows by column in ascending order, after any column already ordered by
+func (qb *QueryBuilderImpl) OrderBy(column string) QueryBuilder {
+	if _, err := quoteIdent(column); err != nil {
+		return qb.withErr(err)
+	}
+
+	q := qb.clone()
+	q.orderBy = append(q.orderBy, orderTerm{column: column})
+	return q
+}
+
+// OrderByDesc sorts the selected rows by column in descending order, after any column already ordered by
+func (qb *QueryBuilderImpl) OrderByDesc(column string) QueryBuilder {
+	if _, err := quoteIdent(column); err != nil {
+		return qb.withErr(err)
+	}
+
+	q := qb.clone()
+	q.orderBy = append(q.orderBy, orderTerm{column: column, desc: true})
+	return q
+}
+
+// Limit selects at most n rows, 0 removes the limit
+func (qb *QueryBuilderImpl) Limit(n int) QueryBuilder {
+	q := qb.clone()
+	q.limit = n
+	return q
+}
+
+// Offset skips the first n selected rows
+func (qb *QueryBuilderImpl) Offset(n int) QueryBuilder {
+	q := qb.clone()
+	q.offset = n
+	return q
+}
+
+// orderClause renders ORDER BY, LIMIT and OFFSET
+func (qb *QueryBuilderImpl) orderClause() string {
+	clauses := []string{}
+
+	if len(qb.orderBy) > 0 {
+		terms := make([]string, 0, len(qb.orderBy))
+		for _, t := range qb.orderBy {
+			if t.desc {
+				terms = append(terms, t.quoted()+" DESC")
+			} else {
+				terms = append(terms, t.quoted())
+			}
+		}
+		clauses = append(clauses, "ORDER BY "+strings.Join(terms, ", "))
+	}
+
+	if qb.limit > 0 {
+		clauses = append(clauses, fmt.Sprintf("LIMIT %d", qb.limit))
+	}
+
+	if qb.offset > 0 {
+		clauses = append(clauses, fmt.Sprintf("OFFSET %d", qb.offset))
+	}
+
+	return strings.Join(clauses, " ")
+}
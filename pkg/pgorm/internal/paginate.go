@@ -0,0 +1,180 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor is returned by KeysetPage for a cursor it did not produce
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+// Page is one page of rows selected by Paginate
+type Page[T any] struct {
+	Items      []T
+	Page       int // 1 based
+	Size       int
+	Total      int64
+	TotalPages int
+}
+
+// Paginate selects page (1 based) of size rows and counts all matching rows.
+// qb should be ordered, otherwise Postgres does not guarantee a stable page content.
+func Paginate[T any](qb QueryBuilder, page, size int) (Page[T], error) {
+	if page < 1 || size < 1 {
+		return Page[T]{}, fmt.Errorf("page and size must be positive, got page %d and size %d", page, size)
+	}
+
+	total, err := qb.Count()
+	if err != nil {
+		return Page[T]{}, err
+	}
+
+	items, err := SelectInto[T](qb.Limit(size).Offset((page - 1) * size))
+	if err != nil {
+		return Page[T]{}, err
+	}
+
+	return Page[T]{
+		Items:      items,
+		Page:       page,
+		Size:       size,
+		Total:      total,
+		TotalPages: int((total + int64(size) - 1) / int64(size)),
+	}, nil
+}
+
+// KeysetPage selects up to size rows following cursor, an empty cursor starting from the first row.
+// Rows are paged on the OrderBy columns of qb, which must be fields of T and together unique,
+// e.g. OrderByDesc("created_at").OrderBy("id"). The returned cursor selects the next page and is
+// empty on the last page. Unlike offsets, pages stay stable when rows are inserted.
+func KeysetPage[T any](qb QueryBuilder, size int, cursor string) ([]T, string, error) {
+	impl, ok := qb.(*QueryBuilderImpl)
+	if !ok {
+		return nil, "", fmt.Errorf("unsupported query builder %T", qb)
+	}
+
+	if len(impl.orderBy) == 0 {
+		return nil, "", fmt.Errorf("keyset pagination requires OrderBy")
+	}
+
+	if size < 1 {
+		return nil, "", fmt.Errorf("size must be positive, got %d", size)
+	}
+
+	if cursor != "" {
+		values, err := decodeCursor(cursor, len(impl.orderBy))
+		if err != nil {
+			return nil, "", err
+		}
+		qb = qb.WhereGroup(func(g QueryBuilder) QueryBuilder {
+			return g.Where(keysetCondition(impl.orderBy), keysetArgs(values)...)
+		})
+	}
+
+	// one extra row tells whether there is a next page
+	items, err := SelectInto[T](qb.Limit(size + 1).Offset(0))
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(items) <= size {
+		return items, "", nil
+	}
+
+	items = items[:size]
+	next, err := encodeCursor(items[size-1], impl.orderBy)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return items, next, nil
+}
+
+// keysetCondition selects the rows after the cursor values:
+// (a > ?) OR (a = ? AND b > ?) OR ..., with < for descending columns
+func keysetCondition(terms []orderTerm) string {
+	ors := make([]string, 0, len(terms))
+
+	for i, t := range terms {
+		ands := make([]string, 0, i+1)
+		for _, prev := range terms[:i] {
+			ands = append(ands, prev.quoted()+" = ?")
+		}
+
+		op := ">"
+		if t.desc {
+			op = "<"
+		}
+		ands = append(ands, fmt.Sprintf("%s %s ?", t.quoted(), op))
+
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+
+	return strings.Join(ors, " OR ")
+}
+
+// keysetArgs repeats the cursor values in the order of the placeholders of keysetCondition
+func keysetArgs(values []interface{}) []interface{} {
+	args := []interface{}{}
+	for i := range values {
+		args = append(args, values[:i+1]...)
+	}
+	return args
+}
+
+func encodeCursor(item interface{}, terms []orderTerm) (string, error) {
+	v := reflect.ValueOf(item)
+	fields := fieldsByColumn(v.Type())
+
+	values := make([]interface{}, 0, len(terms))
+	for _, t := range terms {
+		field, ok := fields[t.column]
+		if !ok {
+			return "", fmt.Errorf("order column %s is not a field of %s", t.column, v.Type())
+		}
+
+		value := v.FieldByIndex(field.index).Interface()
+		if tm, ok := value.(time.Time); ok {
+			// keep the microseconds stored by postgres
+			value = tm.Format(time.RFC3339Nano)
+		}
+		values = append(values, value)
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(cursor string, n int) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	// numbers are kept as text so large ids do not lose precision, postgres casts them
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var values []interface{}
+	if err := decoder.Decode(&values); err != nil || len(values) != n {
+		return nil, ErrInvalidCursor
+	}
+
+	for i, v := range values {
+		if num, ok := v.(json.Number); ok {
+			values[i] = num.String()
+		}
+	}
+
+	return values, nil
+}
@@ -0,0 +1,70 @@
+package internal
+
+import "fmt"
+
+// InsertReturning inserts model and returns the inserted row scanned into T. The columns
+// are those set with Returning, or all columns when none are set.
+func InsertReturning[T any](qb QueryBuilder, model interface{}) ([]T, error) {
+	impl, ok := qb.(*QueryBuilderImpl)
+	if !ok {
+		return nil, fmt.Errorf("unsupported query builder %T", qb)
+	}
+
+	s, err := impl.insertStatement(model)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := queryReturning[T](impl, s)
+	if err != nil {
+		return nil, err
+	}
+
+	return items, afterInsert(s.models)
+}
+
+// UpdateReturning runs the update built by qb and returns every updated row scanned into T,
+// so the number of rows is the number of rows affected
+func UpdateReturning[T any](qb QueryBuilder) ([]T, error) {
+	impl, ok := qb.(*QueryBuilderImpl)
+	if !ok {
+		return nil, fmt.Errorf("unsupported query builder %T", qb)
+	}
+
+	s, err := impl.updateStatement()
+	if err != nil {
+		return nil, err
+	}
+
+	return queryReturning[T](impl, s)
+}
+
+// DeleteReturning runs the delete built by qb and returns every deleted row scanned into T
+func DeleteReturning[T any](qb QueryBuilder) ([]T, error) {
+	impl, ok := qb.(*QueryBuilderImpl)
+	if !ok {
+		return nil, fmt.Errorf("unsupported query builder %T", qb)
+	}
+
+	s, err := impl.deleteStatement()
+	if err != nil {
+		return nil, err
+	}
+
+	return queryReturning[T](impl, s)
+}
+
+func queryReturning[T any](qb *QueryBuilderImpl, s statement) ([]T, error) {
+	returning := qb.returning
+	if returning == "" {
+		returning = "*"
+	}
+
+	rows, err := qb.db.QueryContext(qb.context(), s.query+" RETURNING "+returning, s.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRows[T](rows)
+}
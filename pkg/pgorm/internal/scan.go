@@ -0,0 +1,211 @@
+package internal
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+	bytesType   = reflect.TypeOf([]byte(nil))
+)
+
+// SelectInto runs the select built by qb and scans every row into T, matching columns to `db` tags.
+// Columns without a matching field are ignored.
+func SelectInto[T any](qb QueryBuilder) ([]T, error) {
+	rows, err := qb.Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRows[T](rows)
+}
+
+// First returns the first row selected by qb, or sql.ErrNoRows when there is none
+func First[T any](qb QueryBuilder) (T, error) {
+	var zero T
+
+	rows, err := qb.Limit(1).Rows()
+	if err != nil {
+		return zero, err
+	}
+	defer rows.Close()
+
+	scanner, err := newRowScanner[T](rows)
+	if err != nil {
+		return zero, err
+	}
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return zero, fmt.Errorf("row iteration error: %w", err)
+		}
+		return zero, sql.ErrNoRows
+	}
+
+	return scanner.scan(rows)
+}
+
+// Find selects the rows of the table of T matching condition, e.g. Find[User](qb, "email = ?", email)
+func Find[T Model](qb QueryBuilder, condition string, args ...interface{}) ([]T, error) {
+	var model T
+
+	qb = qb.Table(model)
+	if condition != "" {
+		qb = qb.Where(condition, args...)
+	}
+
+	return SelectInto[T](qb)
+}
+
+func scanRows[T any](rows *sql.Rows) ([]T, error) {
+	scanner, err := newRowScanner[T](rows)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []T{}
+	for rows.Next() {
+		item, err := scanner.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return results, nil
+}
+
+// rowScanner maps the columns of a result set to the fields of T
+type rowScanner[T any] struct {
+	columns []string
+	fields  []*fieldInfo // field of every column, nil when the column is ignored
+}
+
+func newRowScanner[T any](rows *sql.Rows) (*rowScanner[T], error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot scan into %s, expected a struct", t)
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching columns: %w", err)
+	}
+
+	byColumn := fieldsByColumn(t)
+	s := &rowScanner[T]{columns: columns, fields: make([]*fieldInfo, len(columns))}
+	for i, c := range columns {
+		if f, ok := byColumn[c]; ok {
+			s.fields[i] = &f
+			continue
+		}
+		// columns are returned folded to lower case, e.g. createdat for a `db:"createdAt"` field
+		for column, f := range byColumn {
+			if strings.EqualFold(column, c) {
+				s.fields[i] = &f
+				break
+			}
+		}
+	}
+
+	return s, nil
+}
+
+func (s *rowScanner[T]) scan(rows *sql.Rows) (T, error) {
+	var item T
+	v := reflect.ValueOf(&item).Elem()
+
+	dest := make([]interface{}, len(s.columns))
+	var jsonFields []int
+
+	for i, f := range s.fields {
+		if f == nil {
+			dest[i] = new(interface{})
+			continue
+		}
+
+		field := fieldByIndexAlloc(v, f.index)
+		if f.json || isJSONType(field.Type()) {
+			dest[i] = new([]byte)
+			jsonFields = append(jsonFields, i)
+			continue
+		}
+
+		dest[i] = field.Addr().Interface()
+	}
+
+	if err := rows.Scan(dest...); err != nil {
+		return item, fmt.Errorf("error scanning row: %w", err)
+	}
+
+	for _, i := range jsonFields {
+		data := *(dest[i].(*[]byte))
+		if len(data) == 0 {
+			continue
+		}
+
+		field := v.FieldByIndex(s.fields[i].index)
+		if err := json.Unmarshal(data, field.Addr().Interface()); err != nil {
+			return item, fmt.Errorf("error decoding json column %s: %w", s.columns[i], err)
+		}
+	}
+
+	return item, afterFind(&item)
+}
+
+// fieldByIndexAlloc returns the field at index, allocating the nil embedded pointers on the way
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+
+	return v
+}
+
+// fieldsByColumn returns the field mapped to every column through its `db` tag
+func fieldsByColumn(t reflect.Type) map[string]fieldInfo {
+	fields := map[string]fieldInfo{}
+	for _, f := range modelFields(t) {
+		fields[f.column] = f
+	}
+
+	return fields
+}
+
+// isJSONType reports whether values of t are stored as json, i.e. structs, maps and slices
+// the driver cannot scan directly
+func isJSONType(t reflect.Type) bool {
+	if reflect.PointerTo(t).Implements(scannerType) || t == bytesType {
+		return false
+	}
+
+	if t.Kind() == reflect.Ptr {
+		return isJSONType(t.Elem())
+	}
+
+	switch t.Kind() {
+	case reflect.Map, reflect.Slice:
+		return true
+	case reflect.Struct:
+		return t != timeType
+	}
+
+	return false
+}
@@ -0,0 +1,224 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// join is one JOIN clause, its ON condition is numbered when the query is built
+type join struct {
+	kind  string
+	table string
+	on    string
+	args  []interface{}
+}
+
+// Columns selects columns instead of *, e.g. Columns("u.id", "u.name", "o.total AS order_total", "o.*").
+// Use ColumnExpr for expressions such as aggregates.
+func (qb *QueryBuilderImpl) Columns(columns ...string) QueryBuilder {
+	q := qb.clone()
+
+	for _, column := range columns {
+		quoted, err := quoteSelectColumn(column)
+		if err != nil {
+			return q.withErr(err)
+		}
+		q.selectColumns = append(q.selectColumns, quoted)
+	}
+
+	return q
+}
+
+// ColumnExpr selects the SQL expression expr as written, e.g. ColumnExpr("SUM(o.total) AS total").
+// Like conditions it is not validated and must not contain user input.
+func (qb *QueryBuilderImpl) ColumnExpr(expr string) QueryBuilder {
+	q := qb.clone()
+	q.selectColumns = append(q.selectColumns, expr)
+	return q
+}
+
+// Join adds INNER JOIN table ON on, table being a name optionally followed by an alias, e.g.
+// Join("orders o", "o.user_id = u.id AND o.status = ?", "paid")
+func (qb *QueryBuilderImpl) Join(table, on string, args ...interface{}) QueryBuilder {
+	return qb.addJoin("JOIN", table, on, args)
+}
+
+// LeftJoin adds LEFT JOIN table ON on
+func (qb *QueryBuilderImpl) LeftJoin(table, on string, args ...interface{}) QueryBuilder {
+	return qb.addJoin("LEFT JOIN", table, on, args)
+}
+
+func (qb *QueryBuilderImpl) addJoin(kind, table, on string, args []interface{}) QueryBuilder {
+	quoted, err := quoteTableAlias(table)
+	if err != nil {
+		return qb.withErr(err)
+	}
+
+	q := qb.clone()
+	q.joins = append(q.joins, join{kind: kind, table: quoted, on: on, args: args})
+	return q
+}
+
+// GroupBy groups the selected rows by columns
+func (qb *QueryBuilderImpl) GroupBy(columns ...string) QueryBuilder {
+	quoted, err := quoteIdents(columns)
+	if err != nil {
+		return qb.withErr(err)
+	}
+
+	q := qb.clone()
+	q.groupBy = append(q.groupBy, quoted...)
+	return q
+}
+
+// Having adds a condition on the groups joined with AND, e.g. Having("COUNT(*) > ?", 5)
+func (qb *QueryBuilderImpl) Having(expr string, args ...interface{}) QueryBuilder {
+	q := qb.clone()
+	q.having = append(q.having, condition{conjunction: "AND", sql: expr, args: args})
+	return q
+}
+
+// Count returns the number of rows matching the conditions, or the number of groups with GroupBy,
+// ignoring order, limit and offset
+func (qb *QueryBuilderImpl) Count() (int64, error) {
+	if qb.err != nil {
+		return 0, qb.err
+	}
+
+	from, args := qb.fromClause()
+	query := "SELECT COUNT(*) " + from
+	if len(qb.groupBy) > 0 {
+		query = "SELECT COUNT(*) FROM (SELECT 1 " + from + ") AS groups"
+	}
+
+	var count int64
+	if err := qb.db.QueryRowContext(qb.context(), query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count query failed: %w", err)
+	}
+
+	return count, nil
+}
+
+// Exists reports whether a row matches the conditions
+func (qb *QueryBuilderImpl) Exists() (bool, error) {
+	if qb.err != nil {
+		return false, qb.err
+	}
+
+	from, args := qb.fromClause()
+
+	var exists bool
+	if err := qb.db.QueryRowContext(qb.context(), "SELECT EXISTS (SELECT 1 "+from+")", args...).Scan(&exists); err != nil {
+		return false, fmt.Errorf("exists query failed: %w", err)
+	}
+
+	return exists, nil
+}
+
+// selectStatement renders the select of the query
+func (qb *QueryBuilderImpl) selectStatement() statement {
+	columns := qb.selectColumns
+	if len(columns) == 0 {
+		columns = []string{"*"}
+	}
+
+	from, args := qb.fromClause()
+	query := "SELECT " + strings.Join(columns, ", ") + " " + from
+
+	if order := qb.orderClause(); order != "" {
+		query += " " + order
+	}
+
+	return statement{query: query, args: args}
+}
+
+// fromClause renders FROM, JOIN, WHERE, GROUP BY and HAVING
+func (qb *QueryBuilderImpl) fromClause() (string, []interface{}) {
+	clauses := []string{"FROM " + qb.target()}
+	args := []interface{}{}
+
+	for _, j := range qb.joins {
+		on, onArgs := bindPlaceholders(j.on, j.args, len(args))
+		clauses = append(clauses, fmt.Sprintf("%s %s ON %s", j.kind, j.table, on))
+		args = append(args, onArgs...)
+	}
+
+	if where, whereArgs := qb.whereClause(len(args)); where != "" {
+		clauses = append(clauses, where)
+		args = append(args, whereArgs...)
+	}
+
+	if len(qb.groupBy) > 0 {
+		clauses = append(clauses, "GROUP BY "+strings.Join(qb.groupBy, ", "))
+	}
+
+	if len(qb.having) > 0 {
+		having, havingArgs := renderConditions(qb.having, len(args))
+		clauses = append(clauses, "HAVING "+having)
+		args = append(args, havingArgs...)
+	}
+
+	return strings.Join(clauses, " "), args
+}
+
+// Raw runs query as written and scans the rows into T, ? placeholders are numbered and
+// slice arguments expanded as in Where, e.g. Raw[Report](qb, "SELECT ... WHERE id IN (?)", ids)
+func Raw[T any](qb QueryBuilder, query string, args ...interface{}) ([]T, error) {
+	impl, ok := qb.(*QueryBuilderImpl)
+	if !ok {
+		return nil, fmt.Errorf("unsupported query builder %T", qb)
+	}
+
+	query, args = bindPlaceholders(query, args, 0)
+
+	rows, err := impl.db.QueryContext(impl.context(), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query execution error: %w", err)
+	}
+	defer rows.Close()
+
+	return scanRows[T](rows)
+}
+
+// quoteSelectColumn quotes a selected column, which may be *, table.* or aliased with AS
+func quoteSelectColumn(column string) (string, error) {
+	if column == "*" {
+		return column, nil
+	}
+
+	if table, ok := strings.CutSuffix(column, ".*"); ok {
+		quoted, err := quoteIdent(table)
+		return quoted + ".*", err
+	}
+
+	parts := strings.Fields(column)
+	if len(parts) == 3 && strings.EqualFold(parts[1], "AS") {
+		name, err := quoteIdent(parts[0])
+		if err != nil {
+			return "", err
+		}
+		alias, err := quoteIdent(parts[2])
+		return name + " AS " + alias, err
+	}
+
+	return quoteIdent(column)
+}
+
+// quoteTableAlias quotes a joined table, optionally followed by an alias with or without AS
+func quoteTableAlias(table string) (string, error) {
+	parts := strings.Fields(table)
+	if len(parts) == 3 && strings.EqualFold(parts[1], "AS") {
+		parts = []string{parts[0], parts[2]}
+	}
+
+	if len(parts) == 0 || len(parts) > 2 {
+		return "", fmt.Errorf("%w: %q", ErrUnsafeIdentifier, table)
+	}
+
+	quoted, err := quoteIdents(parts)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join(quoted, " "), nil
+}
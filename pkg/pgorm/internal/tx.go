@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Executor runs queries, it is satisfied by both *sql.DB and *sql.Tx
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// txBeginner starts transactions, e.g. *sql.DB
+type txBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// NewQueryBuilderWithExecutor returns a handle running its queries on exec,
+// e.g. a *sql.Tx started outside of pgorm
+func NewQueryBuilderWithExecutor(exec Executor) *QueryBuilderImpl {
+	return &QueryBuilderImpl{db: exec}
+}
+
+// Transaction runs fn in a transaction, committed when fn returns nil and rolled back when it
+// returns an error or panics. The queries of fn must use tx. When qb already runs in a
+// transaction, fn runs in a savepoint so that its failure only rolls back its own work.
+func (qb *QueryBuilderImpl) Transaction(ctx context.Context, fn func(tx QueryBuilder) error) error {
+	return qb.TransactionWithOptions(ctx, nil, fn)
+}
+
+// TransactionWithOptions is Transaction with the isolation level and read only mode of opts,
+// e.g. &sql.TxOptions{Isolation: sql.LevelSerializable}. opts is ignored for savepoints.
+func (qb *QueryBuilderImpl) TransactionWithOptions(ctx context.Context, opts *sql.TxOptions, fn func(tx QueryBuilder) error) error {
+	if _, ok := qb.db.(*sql.Tx); ok {
+		return qb.savepoint(ctx, fn)
+	}
+
+	beginner, ok := qb.db.(txBeginner)
+	if !ok {
+		return fmt.Errorf("cannot begin a transaction on %T", qb.db)
+	}
+
+	tx, err := beginner.BeginTx(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("begin transaction failed: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(&QueryBuilderImpl{db: tx, ctx: ctx}); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return errors.Join(err, fmt.Errorf("rollback failed: %w", rbErr))
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit failed: %w", err)
+	}
+
+	return nil
+}
+
+func (qb *QueryBuilderImpl) savepoint(ctx context.Context, fn func(tx QueryBuilder) error) error {
+	nested := &QueryBuilderImpl{db: qb.db, depth: qb.depth + 1, ctx: ctx}
+	name := fmt.Sprintf("pgorm_savepoint_%d", nested.depth)
+
+	if _, err := qb.db.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("savepoint failed: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			qb.db.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+			panic(p)
+		}
+	}()
+
+	if err := fn(nested); err != nil {
+		if _, rbErr := qb.db.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rbErr != nil {
+			return errors.Join(err, fmt.Errorf("rollback to savepoint failed: %w", rbErr))
+		}
+		return err
+	}
+
+	if _, err := qb.db.ExecContext(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("release savepoint failed: %w", err)
+	}
+
+	return nil
+}
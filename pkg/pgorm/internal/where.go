@@ -0,0 +1,262 @@
+package internal
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// condition is one term of a WHERE clause. Placeholders are kept as ? and only
+// numbered when the query is built, once the position of every argument is known.
+type condition struct {
+	conjunction string // AND or OR, joining the term to the previous one
+	sql         string
+	args        []interface{}
+	group       []condition // set for a parenthesized group
+}
+
+// Where adds a condition joined with AND to the previous ones, e.g. Where("name = ? AND age > ?", name, 18).
+// A slice argument expands to one placeholder per element, e.g. Where("id IN (?)", ids).
+func (qb *QueryBuilderImpl) Where(condition string, args ...interface{}) QueryBuilder {
+	return qb.addCondition("AND", condition, args)
+}
+
+// AndWhere is Where, for readability in long chains
+func (qb *QueryBuilderImpl) AndWhere(condition string, args ...interface{}) QueryBuilder {
+	return qb.addCondition("AND", condition, args)
+}
+
+// OrWhere adds a condition joined with OR to the previous ones
+func (qb *QueryBuilderImpl) OrWhere(condition string, args ...interface{}) QueryBuilder {
+	return qb.addCondition("OR", condition, args)
+}
+
+// WhereGroup adds the conditions built by fn in parentheses, joined with AND, e.g.
+// Where("active").WhereGroup(func(g QueryBuilder) QueryBuilder { return g.Where("a = ?", 1).OrWhere("b = ?", 2) })
+func (qb *QueryBuilderImpl) WhereGroup(fn func(QueryBuilder) QueryBuilder) QueryBuilder {
+	return qb.addGroup("AND", fn)
+}
+
+// OrWhereGroup adds the conditions built by fn in parentheses, joined with OR
+func (qb *QueryBuilderImpl) OrWhereGroup(fn func(QueryBuilder) QueryBuilder) QueryBuilder {
+	return qb.addGroup("OR", fn)
+}
+
+// WhereIn adds column IN (...) with one placeholder per element of values, which must be a slice.
+// An empty slice matches no row.
+func (qb *QueryBuilderImpl) WhereIn(column string, values interface{}) QueryBuilder {
+	return qb.addColumnCondition(column, " IN (?)", []interface{}{values})
+}
+
+// WhereNull adds column IS NULL
+func (qb *QueryBuilderImpl) WhereNull(column string) QueryBuilder {
+	return qb.addColumnCondition(column, " IS NULL", nil)
+}
+
+// WhereNotNull adds column IS NOT NULL
+func (qb *QueryBuilderImpl) WhereNotNull(column string) QueryBuilder {
+	return qb.addColumnCondition(column, " IS NOT NULL", nil)
+}
+
+// WhereEq adds column = value for every non zero field of model with a db tag
+func (qb *QueryBuilderImpl) WhereEq(model interface{}) QueryBuilder {
+	columns, values := modelColumns(model, forFilter)
+	return qb.whereColumns(columns, values)
+}
+
+// WherePK adds column = value for the pk fields of model, e.g. `db:"id,pk"`
+func (qb *QueryBuilderImpl) WherePK(model interface{}) QueryBuilder {
+	v := reflect.Indirect(reflect.ValueOf(model))
+	if v.Kind() != reflect.Struct {
+		return qb.withErr(fmt.Errorf("cannot find the primary key of %T", model))
+	}
+
+	columns := []string{}
+	values := []interface{}{}
+	for _, f := range modelFields(v.Type()) {
+		if f.pk {
+			columns = append(columns, f.column)
+			values = append(values, columnValue(f, v.FieldByIndex(f.index)))
+		}
+	}
+
+	if len(columns) == 0 {
+		return qb.withErr(fmt.Errorf("%T has no pk field", model))
+	}
+
+	return qb.whereColumns(columns, values)
+}
+
+func (qb *QueryBuilderImpl) whereColumns(columns []string, values []interface{}) QueryBuilder {
+	q := qb.clone()
+
+	columns, err := quoteIdents(columns)
+	if err != nil {
+		return q.withErr(err)
+	}
+
+	for i, column := range columns {
+		q.conditions = append(q.conditions, condition{conjunction: "AND", sql: column + " = ?", args: []interface{}{values[i]}})
+	}
+
+	return q
+}
+
+func (qb *QueryBuilderImpl) addCondition(conjunction, sql string, args []interface{}) QueryBuilder {
+	q := qb.clone()
+	q.conditions = append(q.conditions, condition{conjunction: conjunction, sql: sql, args: args})
+	return q
+}
+
+// addColumnCondition adds the condition on column, quoted, followed by sql
+func (qb *QueryBuilderImpl) addColumnCondition(column, sql string, args []interface{}) QueryBuilder {
+	quoted, err := quoteIdent(column)
+	if err != nil {
+		return qb.withErr(err)
+	}
+
+	return qb.addCondition("AND", quoted+sql, args)
+}
+
+func (qb *QueryBuilderImpl) addGroup(conjunction string, fn func(QueryBuilder) QueryBuilder) QueryBuilder {
+	group, ok := fn(&QueryBuilderImpl{}).(*QueryBuilderImpl)
+	if ok && group.err != nil {
+		return qb.withErr(group.err)
+	}
+	if !ok || len(group.conditions) == 0 {
+		return qb
+	}
+
+	q := qb.clone()
+	q.conditions = append(q.conditions, condition{conjunction: conjunction, group: group.conditions})
+	return q
+}
+
+// whereClause renders the conditions, numbering placeholders after the offset arguments already used by the query
+func (qb *QueryBuilderImpl) whereClause(offset int) (string, []interface{}) {
+	conditions := qb.scopedConditions()
+	if len(conditions) == 0 {
+		return "", nil
+	}
+
+	sql, args := renderConditions(conditions, offset)
+	return "WHERE " + sql, args
+}
+
+// scopedConditions returns the conditions with the soft delete filter of the table
+func (qb *QueryBuilderImpl) scopedConditions() []condition {
+	if qb.softDelete == "" || qb.unscoped {
+		return qb.conditions
+	}
+
+	scope := condition{conjunction: "AND", sql: qb.qualifier() + "." + qb.softDelete + " IS NULL"}
+	switch len(qb.conditions) {
+	case 0:
+		return []condition{scope}
+	case 1:
+		return []condition{qb.conditions[0], scope}
+	default:
+		// keep OR conditions from escaping the filter
+		return []condition{{conjunction: "AND", group: qb.conditions}, scope}
+	}
+}
+
+// Unscoped includes the soft deleted rows, and makes Delete remove rows of soft delete models
+func (qb *QueryBuilderImpl) Unscoped() QueryBuilder {
+	q := qb.clone()
+	q.unscoped = true
+	return q
+}
+
+func renderConditions(conditions []condition, offset int) (string, []interface{}) {
+	var b strings.Builder
+	args := []interface{}{}
+
+	for i, c := range conditions {
+		if i > 0 {
+			b.WriteString(" " + c.conjunction + " ")
+		}
+
+		if c.group != nil {
+			sql, groupArgs := renderConditions(c.group, offset+len(args))
+			b.WriteString("(" + sql + ")")
+			args = append(args, groupArgs...)
+			continue
+		}
+
+		sql, condArgs := bindPlaceholders(c.sql, c.args, offset+len(args))
+		if len(conditions) > 1 {
+			sql = "(" + sql + ")"
+		}
+		b.WriteString(sql)
+		args = append(args, condArgs...)
+	}
+
+	return b.String(), args
+}
+
+// bindPlaceholders numbers the ? of condition from startIndex+1, expanding slice arguments
+// into one placeholder per element. Conditions whose ? do not match args are numbered as is.
+func bindPlaceholders(condition string, args []interface{}, startIndex int) (string, []interface{}) {
+	if strings.Count(condition, "?") != len(args) {
+		return replacePlaceholders(condition, startIndex), args
+	}
+
+	var b strings.Builder
+	bound := []interface{}{}
+	next := 0
+
+	for _, char := range condition {
+		if char != '?' {
+			b.WriteRune(char)
+			continue
+		}
+
+		arg := args[next]
+		next++
+
+		elems, ok := expandSlice(arg)
+		if !ok {
+			bound = append(bound, arg)
+			fmt.Fprintf(&b, "$%d", startIndex+len(bound))
+			continue
+		}
+
+		if len(elems) == 0 {
+			// IN () is invalid, IN (NULL) matches nothing
+			b.WriteString("NULL")
+			continue
+		}
+
+		for i, e := range elems {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			bound = append(bound, e)
+			fmt.Fprintf(&b, "$%d", startIndex+len(bound))
+		}
+	}
+
+	return b.String(), bound
+}
+
+// expandSlice returns the elements of arg when it is a slice or array to expand,
+// byte slices and driver values (e.g. pq.Array) are bound as a single argument
+func expandSlice(arg interface{}) ([]interface{}, bool) {
+	if _, ok := arg.(driver.Valuer); ok {
+		return nil, false
+	}
+
+	v := reflect.ValueOf(arg)
+	if !v.IsValid() || (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || v.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+
+	elems := make([]interface{}, v.Len())
+	for i := range elems {
+		elems[i] = v.Index(i).Interface()
+	}
+
+	return elems, true
+}
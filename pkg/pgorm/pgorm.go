@@ -7,6 +7,98 @@ import (
 	"github.com/phil-inc/pcommon/pkg/pgorm/internal"
 )
 
+type (
+	QueryBuilder = internal.QueryBuilder
+	Model        = internal.Model
+	Result       = internal.Result
+	Executor     = internal.Executor
+
+	// Optional model hooks, see the README
+	BeforeInserter = internal.BeforeInserter
+	AfterInserter  = internal.AfterInserter
+	BeforeUpdater  = internal.BeforeUpdater
+	AfterFinder    = internal.AfterFinder
+	Validator      = internal.Validator
+)
+
 func NewQueryBuilder(DB *sql.DB) *internal.QueryBuilderImpl {
 	return internal.NewQueryBuilder(DB)
 }
+
+// NewQueryBuilderWithExecutor returns a handle running its queries on exec, e.g. an existing *sql.Tx
+func NewQueryBuilderWithExecutor(exec Executor) *internal.QueryBuilderImpl {
+	return internal.NewQueryBuilderWithExecutor(exec)
+}
+
+// SelectInto runs the select built by qb and scans every row into T using its `db` tags
+func SelectInto[T any](qb QueryBuilder) ([]T, error) {
+	return internal.SelectInto[T](qb)
+}
+
+// First returns the first row selected by qb, or sql.ErrNoRows when there is none
+func First[T any](qb QueryBuilder) (T, error) {
+	return internal.First[T](qb)
+}
+
+// Find selects the rows of the table of T matching condition
+func Find[T Model](qb QueryBuilder, condition string, args ...interface{}) ([]T, error) {
+	return internal.Find[T](qb, condition, args...)
+}
+
+// Raw runs query as written and scans the rows into T, ? placeholders are numbered as in Where
+func Raw[T any](qb QueryBuilder, query string, args ...interface{}) ([]T, error) {
+	return internal.Raw[T](qb, query, args...)
+}
+
+// InsertReturning inserts model and returns the inserted row scanned into T
+func InsertReturning[T any](qb QueryBuilder, model interface{}) ([]T, error) {
+	return internal.InsertReturning[T](qb, model)
+}
+
+// UpdateReturning runs the update built by qb and returns every updated row scanned into T
+func UpdateReturning[T any](qb QueryBuilder) ([]T, error) {
+	return internal.UpdateReturning[T](qb)
+}
+
+// DeleteReturning runs the delete built by qb and returns every deleted row scanned into T
+func DeleteReturning[T any](qb QueryBuilder) ([]T, error) {
+	return internal.DeleteReturning[T](qb)
+}
+
+// UpsertInto upserts model into its table and returns the inserted or updated row,
+// or sql.ErrNoRows when the row was left as is
+func UpsertInto[T Model](qb QueryBuilder, model T, conflictColumns []string, updateColumns ...string) (T, error) {
+	return internal.UpsertInto[T](qb, model, conflictColumns, updateColumns...)
+}
+
+var (
+	// ErrInvalidCursor is returned by KeysetPage for a cursor it did not produce
+	ErrInvalidCursor = internal.ErrInvalidCursor
+
+	// ErrUnsafeIdentifier is returned for a table or column name that is not a plain identifier
+	ErrUnsafeIdentifier = internal.ErrUnsafeIdentifier
+
+	// ErrMissingWhere is returned by Update and Delete without conditions, unless AllowAllRows is set
+	ErrMissingWhere = internal.ErrMissingWhere
+)
+
+// Page is one page of rows selected by Paginate
+type Page[T any] struct {
+	Items      []T
+	Page       int // 1 based
+	Size       int
+	Total      int64
+	TotalPages int
+}
+
+// Paginate selects page (1 based) of size rows of qb and counts all matching rows
+func Paginate[T any](qb QueryBuilder, page, size int) (Page[T], error) {
+	p, err := internal.Paginate[T](qb, page, size)
+	return Page[T](p), err
+}
+
+// KeysetPage selects up to size rows of qb following cursor, paging on its OrderBy columns.
+// The returned cursor selects the next page and is empty on the last page.
+func KeysetPage[T any](qb QueryBuilder, size int, cursor string) ([]T, string, error) {
+	return internal.KeysetPage[T](qb, size, cursor)
+}